@@ -2,14 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/akansha204/cryptex-secretservice/internal/database"
 	"github.com/akansha204/cryptex-secretservice/internal/repository"
 	"github.com/akansha204/cryptex-secretservice/internal/routes"
+	"github.com/akansha204/cryptex-secretservice/internal/services"
 	"github.com/akansha204/cryptex-secretservice/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
@@ -23,20 +26,77 @@ func main() {
 
 	database.ConnectDB()
 
-	app := fiber.New()
+	proxyHeader, trustedProxies, err := utils.LoadProxyConfig()
+	if err != nil {
+		panic(err)
+	}
+	app := fiber.New(fiber.Config{
+		ProxyHeader:             proxyHeader,
+		EnableTrustedProxyCheck: true,
+		TrustedProxies:          trustedProxies,
+		EnableIPValidation:      true,
+	})
 	routes.SetupRoutes(app)
+
+	// the background jobs share one set of services
+	secretRepo := repository.NewSecretRepository()
+	grantRepo := repository.NewAccessGrantRepository()
+	breakGlassRepo := repository.NewBreakGlassRepository()
+
+	auditService := services.NewAuditService(repository.NewAuditRepository())
+	authorizer := services.NewAuthorizer(repository.NewProjectRepository(), repository.NewMemberRepository(), repository.NewPolicyRepository(), repository.NewOrgRepository(), grantRepo, breakGlassRepo)
+	notificationService := services.NewNotificationService(
+		repository.NewNotificationRepository(),
+		authorizer,
+		secretRepo,
+		auditService,
+	)
+	webhookService := services.NewWebhookService(
+		repository.NewWebhookRepository(),
+		authorizer,
+		auditService,
+	)
+	rotationService := services.NewRotationService(
+		repository.NewRotationRepository(),
+		secretRepo,
+		authorizer,
+		auditService,
+		notificationService,
+		webhookService,
+	)
+	leaseService := services.NewLeaseService(
+		repository.NewLeaseRepository(),
+		secretRepo,
+		authorizer,
+		auditService,
+		webhookService,
+	)
+	accessGrantService := services.NewAccessGrantService(grantRepo, authorizer, auditService)
+
 	startAutoPurgeJob()
-	app.Listen(":3000")
+	startRotationScheduler(rotationService)
+	startExpiryNotifier(notificationService)
+	startWebhookDispatcher(webhookService)
+	startLeaseSweeper(leaseService)
+	startGrantSweeper(accessGrantService)
+
+	tlsConfig, err := utils.LoadTLSConfig()
+	if err != nil {
+		panic(err)
+	}
+	if tlsConfig == nil {
+		app.Listen(":3000")
+		return
+	}
+	ln, err := tls.Listen("tcp", ":3000", tlsConfig)
+	if err != nil {
+		panic(err)
+	}
+	app.Listener(ln)
 }
 func startAutoPurgeJob() {
 	purgeRepo := repository.NewPurgeRepository()
 
-	daysStr := os.Getenv("PURGE_DAYS")
-	if daysStr == "" {
-		daysStr = "7" // bydefault 7 days
-	}
-	days, _ := strconv.Atoi(daysStr)
-
 	go func() {
 		ticker := time.NewTicker(24 * time.Hour) // run every 24hrs
 		defer ticker.Stop()
@@ -46,8 +106,7 @@ func startAutoPurgeJob() {
 
 			ctx := context.Background()
 
-			olderThan := time.Duration(days) * 24 * time.Hour
-			// olderThan := time.Duration(days) * time.Minute
+			olderThan := utils.PurgeRetention()
 			err := purgeRepo.PurgeOldData(ctx, olderThan)
 			if err != nil {
 				fmt.Println("[PURGE ERROR]", err)
@@ -57,3 +116,156 @@ func startAutoPurgeJob() {
 		}
 	}()
 }
+
+func startRotationScheduler(rotationService *services.RotationService) {
+	secondsStr := os.Getenv("ROTATION_CHECK_SECONDS")
+	if secondsStr == "" {
+		secondsStr = "60" // check for due rotations every minute by default
+	}
+	seconds, err := strconv.Atoi(secondsStr)
+	if err != nil || seconds < 1 {
+		seconds = 60
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+
+			ctx := context.Background()
+
+			count, err := rotationService.RunDue(ctx)
+			if err != nil {
+				fmt.Println("[ROTATION ERROR]", err)
+			} else if count > 0 {
+				fmt.Println("[ROTATION] Processed", count, "due rotations")
+			}
+		}
+	}()
+}
+
+func startExpiryNotifier(notificationService *services.NotificationService) {
+	thresholdsStr := os.Getenv("EXPIRY_WARNING_THRESHOLDS")
+	if thresholdsStr == "" {
+		thresholdsStr = "168h,24h,1h" // warn a week, a day and an hour before expiry
+	}
+	var thresholds []time.Duration
+	for _, part := range strings.Split(thresholdsStr, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(part))
+		if err != nil || d <= 0 {
+			fmt.Println("[EXPIRY] ignoring invalid threshold", part)
+			continue
+		}
+		thresholds = append(thresholds, d)
+	}
+
+	minutesStr := os.Getenv("EXPIRY_CHECK_MINUTES")
+	if minutesStr == "" {
+		minutesStr = "5"
+	}
+	minutes, err := strconv.Atoi(minutesStr)
+	if err != nil || minutes < 1 {
+		minutes = 5
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(minutes) * time.Minute)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+
+			ctx := context.Background()
+
+			if _, err := notificationService.CheckExpiring(ctx, thresholds); err != nil {
+				fmt.Println("[EXPIRY ERROR]", err)
+			}
+		}
+	}()
+}
+
+func startWebhookDispatcher(webhookService *services.WebhookService) {
+	secondsStr := os.Getenv("WEBHOOK_DISPATCH_SECONDS")
+	if secondsStr == "" {
+		secondsStr = "10"
+	}
+	seconds, err := strconv.Atoi(secondsStr)
+	if err != nil || seconds < 1 {
+		seconds = 10
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+
+			ctx := context.Background()
+
+			if _, err := webhookService.DispatchDue(ctx); err != nil {
+				fmt.Println("[WEBHOOK ERROR]", err)
+			}
+		}
+	}()
+}
+
+func startLeaseSweeper(leaseService *services.LeaseService) {
+	secondsStr := os.Getenv("LEASE_SWEEP_SECONDS")
+	if secondsStr == "" {
+		secondsStr = "30"
+	}
+	seconds, err := strconv.Atoi(secondsStr)
+	if err != nil || seconds < 1 {
+		seconds = 30
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+
+			ctx := context.Background()
+
+			count, err := leaseService.ExpireDue(ctx)
+			if err != nil {
+				fmt.Println("[LEASE ERROR]", err)
+			} else if count > 0 {
+				fmt.Println("[LEASE] Expired", count, "leases")
+			}
+		}
+	}()
+}
+
+func startGrantSweeper(accessGrantService *services.AccessGrantService) {
+	secondsStr := os.Getenv("GRANT_SWEEP_SECONDS")
+	if secondsStr == "" {
+		secondsStr = "60"
+	}
+	seconds, err := strconv.Atoi(secondsStr)
+	if err != nil || seconds < 1 {
+		seconds = 60
+	}
+
+	go func() {
+		ticker := time.NewTicker(time.Duration(seconds) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			<-ticker.C
+
+			ctx := context.Background()
+
+			count, err := accessGrantService.ExpireDue(ctx)
+			if err != nil {
+				fmt.Println("[GRANT ERROR]", err)
+			} else if count > 0 {
+				fmt.Println("[GRANT] Expired", count, "access grants")
+			}
+		}
+	}()
+}
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AccessGrantController struct {
+	service *services.AccessGrantService
+}
+
+func NewAccessGrantController(service *services.AccessGrantService) *AccessGrantController {
+	return &AccessGrantController{service: service}
+}
+
+type AccessGrantBody struct {
+	Path          string          `json:"path"`     // secret name glob, default the whole project
+	Duration      *utils.Duration `json:"duration"` // e.g. "4h", at most a day
+	Justification string          `json:"justification"`
+}
+
+func (gc *AccessGrantController) RequestGrant(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body AccessGrantBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	grant, err := gc.service.RequestGrant(c.Context(), userID, projectID, services.AccessGrantInput{
+		Path:          body.Path,
+		Duration:      body.Duration.Value(),
+		Justification: body.Justification,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(grant)
+}
+
+func (gc *AccessGrantController) ListGrants(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	grants, err := gc.service.ListGrants(c.Context(), userID, projectID, c.Query("status"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(grants)
+}
+
+func (gc *AccessGrantController) ApproveGrant(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	grantID := c.Params("grantId")
+
+	grant, err := gc.service.ApproveGrant(c.Context(), userID, projectID, grantID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(grant)
+}
+
+func (gc *AccessGrantController) RejectGrant(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	grantID := c.Params("grantId")
+
+	grant, err := gc.service.RejectGrant(c.Context(), userID, projectID, grantID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(grant)
+}
+
+func (gc *AccessGrantController) RevokeGrant(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	grantID := c.Params("grantId")
+
+	grant, err := gc.service.RevokeGrant(c.Context(), userID, projectID, grantID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(grant)
+}
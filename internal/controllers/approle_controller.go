@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type AppRoleController struct {
+	service *services.AppRoleService
+}
+
+func NewAppRoleController(service *services.AppRoleService) *AppRoleController {
+	return &AppRoleController{service: service}
+}
+
+type AppRoleBody struct {
+	Name            string          `json:"name"`
+	Paths           []string        `json:"paths"`              // "<projectId>/<secret glob>"
+	Scopes          []string        `json:"scopes"`             // default read and list
+	TokenTTL        *utils.Duration `json:"token_ttl"`          // default 15 minutes, at most 1 hour
+	SecretIDTTL     *utils.Duration `json:"secret_id_ttl"`      // default never expires
+	SecretIDNumUses *int            `json:"secret_id_num_uses"` // default unlimited
+}
+
+type AppRoleLoginBody struct {
+	RoleID   string `json:"role_id"`
+	SecretID string `json:"secret_id"`
+}
+
+func (ac *AppRoleController) Login(c *fiber.Ctx) error {
+	var body AppRoleLoginBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	token, expiresAt, err := ac.service.Login(c.Context(), body.RoleID, body.SecretID, c.IP())
+	if err != nil {
+		return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"token":      token,
+		"expires_at": expiresAt,
+	})
+}
+
+func (ac *AppRoleController) CreateRole(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body AppRoleBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	role, err := ac.service.CreateRole(c.Context(), userID, services.AppRoleInput{
+		Name:            body.Name,
+		Paths:           body.Paths,
+		Scopes:          body.Scopes,
+		TokenTTL:        body.TokenTTL.Value(),
+		SecretIDTTL:     body.SecretIDTTL.Value(),
+		SecretIDNumUses: body.SecretIDNumUses,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(role)
+}
+
+func (ac *AppRoleController) ListRoles(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	roles, err := ac.service.ListRoles(c.Context(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(roles)
+}
+
+func (ac *AppRoleController) GetRole(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	roleID := c.Params("roleId")
+
+	role, err := ac.service.GetRole(c.Context(), userID, roleID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(role)
+}
+
+func (ac *AppRoleController) DeleteRole(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	roleID := c.Params("roleId")
+
+	if err := ac.service.DeleteRole(c.Context(), userID, roleID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "approle deleted"})
+}
+
+func (ac *AppRoleController) GenerateSecretID(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	roleID := c.Params("roleId")
+
+	secretID, plaintext, err := ac.service.GenerateSecretID(c.Context(), userID, roleID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// the secret ID itself is only returned once, on creation
+	return c.Status(201).JSON(fiber.Map{
+		"secret_id":      plaintext,
+		"secret_id_info": secretID,
+	})
+}
+
+func (ac *AppRoleController) ListSecretIDs(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	roleID := c.Params("roleId")
+
+	secretIDs, err := ac.service.ListSecretIDs(c.Context(), userID, roleID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(secretIDs)
+}
+
+func (ac *AppRoleController) DestroySecretID(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	roleID := c.Params("roleId")
+	accessor := c.Params("accessor")
+
+	if err := ac.service.DestroySecretID(c.Context(), userID, roleID, accessor); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "secret id destroyed"})
+}
@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type BreakGlassController struct {
+	service *services.BreakGlassService
+}
+
+func NewBreakGlassController(service *services.BreakGlassService) *BreakGlassController {
+	return &BreakGlassController{service: service}
+}
+
+type BreakGlassBody struct {
+	Reason string          `json:"reason"`
+	TTL    *utils.Duration `json:"ttl"` // default 1 hour, at most 4 hours
+}
+
+func (bc *BreakGlassController) OpenSession(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body BreakGlassBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	session, err := bc.service.OpenSession(c.Context(), userID, projectID, body.Reason, body.TTL.Value())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(session)
+}
+
+func (bc *BreakGlassController) ListSessions(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	sessions, err := bc.service.ListSessions(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(sessions)
+}
+
+func (bc *BreakGlassController) EndSession(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	sessionID := c.Params("sessionId")
+
+	session, err := bc.service.EndSession(c.Context(), userID, projectID, sessionID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(session)
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ChangeRequestController struct {
+	service *services.ChangeRequestService
+}
+
+func NewChangeRequestController(service *services.ChangeRequestService) *ChangeRequestController {
+	return &ChangeRequestController{service: service}
+}
+
+type ChangeRequestCommentBody struct {
+	Comment string `json:"comment"`
+}
+
+func (cc *ChangeRequestController) ListChangeRequests(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	requests, err := cc.service.ListChangeRequests(c.Context(), userID, projectID, c.Query("status"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(requests)
+}
+
+func (cc *ChangeRequestController) GetChangeRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	requestID := c.Params("crId")
+
+	request, err := cc.service.GetChangeRequest(c.Context(), userID, projectID, requestID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(request)
+}
+
+func (cc *ChangeRequestController) ApproveChangeRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	requestID := c.Params("crId")
+
+	var body ChangeRequestCommentBody
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+		}
+	}
+
+	request, err := cc.service.ApproveChangeRequest(c.Context(), userID, projectID, requestID, body.Comment)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(request)
+}
+
+func (cc *ChangeRequestController) RejectChangeRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	requestID := c.Params("crId")
+
+	var body ChangeRequestCommentBody
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+		}
+	}
+
+	request, err := cc.service.RejectChangeRequest(c.Context(), userID, projectID, requestID, body.Comment)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(request)
+}
+
+func (cc *ChangeRequestController) CancelChangeRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	requestID := c.Params("crId")
+
+	request, err := cc.service.CancelChangeRequest(c.Context(), userID, projectID, requestID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(request)
+}
+
+func (cc *ChangeRequestController) CommentChangeRequest(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	requestID := c.Params("crId")
+
+	var body ChangeRequestCommentBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	comment, err := cc.service.CommentChangeRequest(c.Context(), userID, projectID, requestID, body.Comment)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(comment)
+}
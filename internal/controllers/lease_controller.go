@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type LeaseController struct {
+	service *services.LeaseService
+}
+
+func NewLeaseController(service *services.LeaseService) *LeaseController {
+	return &LeaseController{service: service}
+}
+
+type LeaseBody struct {
+	LeaseID   string          `json:"lease_id"`
+	Increment *utils.Duration `json:"increment"` // defaults to the secret's lease ttl
+}
+
+type LeasePrefixBody struct {
+	Prefix string `json:"prefix"` // secret name prefix, "" for every lease in the project
+}
+
+func (lc *LeaseController) RenewLease(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body LeaseBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.LeaseID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "lease_id is required"})
+	}
+
+	lease, err := lc.service.Renew(c.Context(), userID, body.LeaseID, body.Increment.Value())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(lease)
+}
+
+func (lc *LeaseController) RevokeLease(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body LeaseBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.LeaseID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "lease_id is required"})
+	}
+
+	if err := lc.service.Revoke(c.Context(), userID, body.LeaseID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "lease revoked"})
+}
+
+func (lc *LeaseController) ListLeases(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	leases, err := lc.service.ListLeases(c.Context(), userID, projectID, c.Query("prefix"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(leases)
+}
+
+func (lc *LeaseController) RevokePrefix(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body LeasePrefixBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	count, err := lc.service.RevokePrefix(c.Context(), userID, projectID, body.Prefix)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"revoked": count})
+}
@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MemberController struct {
+	service *services.MemberService
+}
+
+func NewMemberController(service *services.MemberService) *MemberController {
+	return &MemberController{service: service}
+}
+
+type MemberBody struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"` // owner, admin, writer, reader or metadata-only
+}
+
+func (mc *MemberController) ListMembers(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	members, err := mc.service.ListMembers(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(members)
+}
+
+func (mc *MemberController) AddMember(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body MemberBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.UserID == "" || body.Role == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_id and role are required"})
+	}
+
+	member, err := mc.service.AddMember(c.Context(), userID, projectID, body.UserID, body.Role)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(member)
+}
+
+func (mc *MemberController) UpdateMember(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	memberID := c.Params("userId")
+
+	var body MemberBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.Role == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "role is required"})
+	}
+
+	member, err := mc.service.UpdateMember(c.Context(), userID, projectID, memberID, body.Role)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(member)
+}
+
+func (mc *MemberController) RemoveMember(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	memberID := c.Params("userId")
+
+	if err := mc.service.RemoveMember(c.Context(), userID, projectID, memberID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "member removed"})
+}
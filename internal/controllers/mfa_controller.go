@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type MFAController struct {
+	service *services.MFAService
+}
+
+func NewMFAController(service *services.MFAService) *MFAController {
+	return &MFAController{service: service}
+}
+
+type TOTPCodeBody struct {
+	Code string `json:"code"`
+}
+
+func (mc *MFAController) GetTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	factor, err := mc.service.GetFactor(c.Context(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	if factor == nil {
+		return c.JSON(fiber.Map{"enrolled": false})
+	}
+	return c.JSON(fiber.Map{
+		"enrolled":     factor.ConfirmedAt != nil,
+		"confirmed_at": factor.ConfirmedAt,
+		"last_used_at": factor.LastUsedAt,
+	})
+}
+
+func (mc *MFAController) EnrollTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	enrollment, err := mc.service.EnrollTOTP(c.Context(), userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	// the secret is only returned here; confirm with a first code to activate it
+	return c.Status(201).JSON(enrollment)
+}
+
+func (mc *MFAController) ConfirmTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body TOTPCodeBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	if err := mc.service.ConfirmTOTP(c.Context(), userID, body.Code); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "TOTP factor enrolled"})
+}
+
+// RemoveTOTP takes a current code in X-Cryptex-TOTP
+func (mc *MFAController) RemoveTOTP(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	if err := mc.service.RemoveTOTP(c.Context(), userID, c.Get("X-Cryptex-TOTP")); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "TOTP factor removed"})
+}
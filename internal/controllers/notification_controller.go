@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type NotificationController struct {
+	service *services.NotificationService
+}
+
+func NewNotificationController(service *services.NotificationService) *NotificationController {
+	return &NotificationController{service: service}
+}
+
+type NotificationChannelBody struct {
+	Name          string  `json:"name"`
+	WebhookURL    string  `json:"webhook_url"`
+	SigningSecret *string `json:"signing_secret"` // optional HMAC key for X-Cryptex-Signature
+}
+
+func (nc *NotificationController) CreateChannel(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body NotificationChannelBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.Name == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
+	}
+	if body.WebhookURL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "webhook_url is required"})
+	}
+
+	channel, err := nc.service.CreateChannel(c.Context(), userID, projectID, body.Name, body.WebhookURL, body.SigningSecret)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(channel)
+}
+
+func (nc *NotificationController) ListChannels(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	channels, err := nc.service.ListChannels(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(channels)
+}
+
+func (nc *NotificationController) DeleteChannel(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	channelID := c.Params("channelId")
+
+	err := nc.service.DeleteChannel(c.Context(), userID, projectID, channelID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "notification channel deleted"})
+}
@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type OrgController struct {
+	service *services.OrgService
+}
+
+func NewOrgController(service *services.OrgService) *OrgController {
+	return &OrgController{service: service}
+}
+
+type OrgBody struct {
+	Slug string `json:"slug"` // as sent by the gateway in X-User-Orgs / X-User-Teams
+	Name string `json:"name"`
+}
+
+func (oc *OrgController) CreateOrg(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body OrgBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	org, err := oc.service.CreateOrg(c.Context(), userID, body.Slug, body.Name)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(org)
+}
+
+func (oc *OrgController) ListOrgs(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	orgs, err := oc.service.ListOrgs(c.Context(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(orgs)
+}
+
+func (oc *OrgController) GetOrg(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	orgID := c.Params("orgId")
+
+	org, err := oc.service.GetOrg(c.Context(), userID, orgID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(org)
+}
+
+func (oc *OrgController) ListProjects(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	orgID := c.Params("orgId")
+
+	projects, err := oc.service.ListProjects(c.Context(), userID, orgID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(projects)
+}
+
+func (oc *OrgController) CreateTeam(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	orgID := c.Params("orgId")
+
+	var body OrgBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	team, err := oc.service.CreateTeam(c.Context(), userID, orgID, body.Slug, body.Name)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(team)
+}
+
+func (oc *OrgController) ListTeams(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	orgID := c.Params("orgId")
+
+	teams, err := oc.service.ListTeams(c.Context(), userID, orgID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(teams)
+}
+
+func (oc *OrgController) DeleteTeam(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	orgID := c.Params("orgId")
+	teamID := c.Params("teamId")
+
+	if err := oc.service.DeleteTeam(c.Context(), userID, orgID, teamID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "team deleted"})
+}
@@ -0,0 +1,159 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type PolicyController struct {
+	service *services.PolicyService
+}
+
+func NewPolicyController(service *services.PolicyService) *PolicyController {
+	return &PolicyController{service: service}
+}
+
+type PolicyBody struct {
+	Name        string              `json:"name"`
+	Description *string             `json:"description"`
+	Rules       []models.PolicyRule `json:"rules"`
+}
+
+type AttachmentBody struct {
+	PrincipalType string `json:"principal_type"` // user, group or service_account
+	PrincipalID   string `json:"principal_id"`
+}
+
+type EvaluateBody struct {
+	UserID     string   `json:"user_id"` // defaults to the caller
+	Groups     []string `json:"groups"`
+	ProjectID  string   `json:"project_id"`
+	SecretName string   `json:"secret_name"` // empty evaluates the project itself
+	Capability string   `json:"capability"`
+}
+
+func (pc *PolicyController) CreatePolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body PolicyBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	policy, err := pc.service.CreatePolicy(c.Context(), userID, services.PolicyInput{
+		Name:        body.Name,
+		Description: body.Description,
+		Rules:       body.Rules,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(policy)
+}
+
+func (pc *PolicyController) ListPolicies(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	policies, err := pc.service.ListPolicies(c.Context(), userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(policies)
+}
+
+func (pc *PolicyController) GetPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	policyID := c.Params("policyId")
+
+	policy, attachments, err := pc.service.GetPolicy(c.Context(), userID, policyID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"policy":      policy,
+		"attachments": attachments,
+	})
+}
+
+func (pc *PolicyController) UpdatePolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	policyID := c.Params("policyId")
+
+	var body PolicyBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	policy, err := pc.service.UpdatePolicy(c.Context(), userID, policyID, services.PolicyInput{
+		Name:        body.Name,
+		Description: body.Description,
+		Rules:       body.Rules,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(policy)
+}
+
+func (pc *PolicyController) DeletePolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	policyID := c.Params("policyId")
+
+	if err := pc.service.DeletePolicy(c.Context(), userID, policyID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "policy deleted"})
+}
+
+func (pc *PolicyController) AttachPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	policyID := c.Params("policyId")
+
+	var body AttachmentBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	attachment, err := pc.service.AttachPolicy(c.Context(), userID, policyID, body.PrincipalType, body.PrincipalID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(attachment)
+}
+
+func (pc *PolicyController) DetachPolicy(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	policyID := c.Params("policyId")
+	attachmentID := c.Params("attachmentId")
+
+	if err := pc.service.DetachPolicy(c.Context(), userID, policyID, attachmentID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "policy detached"})
+}
+
+// Evaluate explains why a request would be allowed or denied
+func (pc *PolicyController) Evaluate(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	var body EvaluateBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.ProjectID == "" || body.Capability == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "project_id and capability are required"})
+	}
+
+	decision, err := pc.service.Evaluate(c.Context(), userID, services.EvaluateInput{
+		UserID:     body.UserID,
+		Groups:     body.Groups,
+		ProjectID:  body.ProjectID,
+		SecretName: body.SecretName,
+		Permission: services.Permission(body.Capability),
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(decision)
+}
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -14,8 +15,20 @@ func NewProjectController(service *services.ProjectService) *ProjectController {
 }
 
 type Projectbody struct {
-	Name        string  `json:"name"`
-	Description *string `json:"description"`
+	Name        string          `json:"name"`
+	Description *string         `json:"description"`
+	MinTTL      *utils.Duration `json:"min_ttl"` // lifetime bounds for secrets, e.g. "15m" or "P30D"; "0" removes a bound
+	MaxTTL      *utils.Duration `json:"max_ttl"`
+
+	RequireApproval *bool     `json:"require_approval"` // secret changes need a second member's approval
+	AllowedCIDRs    *[]string `json:"allowed_cidrs"`    // networks secrets may be read from; [] clears the allowlist
+	OwnerType       string    `json:"owner_type"`       // on create: team or org, defaults to the caller
+	OwnerID         string    `json:"owner_id"`
+}
+
+type TransferBody struct {
+	OwnerType string `json:"owner_type"` // user, team or org
+	OwnerID   string `json:"owner_id"`
 }
 
 func (pc *ProjectController) CreateProject(c *fiber.Ctx) error {
@@ -29,7 +42,8 @@ func (pc *ProjectController) CreateProject(c *fiber.Ctx) error {
 	if body.Name == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
 	}
-	project, err := pc.service.CreateProject(c.Context(), userID, body.Name, body.Description)
+	owner := services.ProjectOwner{Type: body.OwnerType, ID: body.OwnerID}
+	project, err := pc.service.CreateProject(c.Context(), userID, body.Name, body.Description, body.MinTTL.Value(), body.MaxTTL.Value(), body.RequireApproval, body.AllowedCIDRs, owner)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -39,17 +53,10 @@ func (pc *ProjectController) CreateProject(c *fiber.Ctx) error {
 func (pc *ProjectController) GetProject(c *fiber.Ctx) error {
 	userID := c.Locals("userId").(string)
 	projectID := c.Params("id")
-	project, err := pc.service.GetProjectByID(c.Context(), projectID)
+	project, err := pc.service.GetProjectByID(c.Context(), projectID, userID)
 
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
-	}
-
-	if project == nil {
-		return c.Status(404).JSON(fiber.Map{"error": "project not found"})
-	}
-	if project.UserID.String() != userID {
-		return c.Status(403).JSON(fiber.Map{"error": "forbidden: you do not own this project"})
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	return c.JSON(project)
@@ -74,7 +81,7 @@ func (pc *ProjectController) UpdateProject(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
 	}
 
-	project, err := pc.service.UpdateProject(c.Context(), projectID, userID, body.Name, body.Description)
+	project, err := pc.service.UpdateProject(c.Context(), projectID, userID, body.Name, body.Description, body.MinTTL.Value(), body.MaxTTL.Value(), body.RequireApproval, body.AllowedCIDRs)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
@@ -93,3 +100,58 @@ func (pc *ProjectController) DeleteProject(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{"message": "project deleted"})
 }
+
+func (pc *ProjectController) ListTrash(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+
+	projects, err := pc.service.ListTrash(c.Context(), userID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(projects)
+}
+
+func (pc *ProjectController) RestoreProject(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	project, err := pc.service.RestoreProject(c.Context(), projectID, userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(project)
+}
+
+func (pc *ProjectController) PurgeProject(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	err := pc.service.PurgeProject(c.Context(), projectID, userID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "project permanently deleted"})
+}
+
+func (pc *ProjectController) TransferProject(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body TransferBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.OwnerType == "" || body.OwnerID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "owner_type and owner_id are required"})
+	}
+
+	project, err := pc.service.TransferProject(c.Context(), projectID, userID, services.ProjectOwner{Type: body.OwnerType, ID: body.OwnerID})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(project)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type RotationController struct {
+	service *services.RotationService
+}
+
+func NewRotationController(service *services.RotationService) *RotationController {
+	return &RotationController{service: service}
+}
+
+type RotationPolicyBody struct {
+	IntervalHours *int                  `json:"interval_hours"` // rotate every N hours
+	Cron          *string               `json:"cron"`           // or on a cron schedule
+	Rotator       string                `json:"rotator"`        // "generator" (default) or "webhook"
+	Generate      *utils.GeneratePolicy `json:"generate"`
+	WebhookURL    *string               `json:"webhook_url"`
+	WebhookSecret *string               `json:"webhook_secret"` // optional HMAC key for the webhook call
+	Enabled       *bool                 `json:"enabled"`
+}
+
+func (rc *RotationController) SetPolicy(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	var body RotationPolicyBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request json"})
+	}
+
+	policy, err := rc.service.SetPolicy(
+		c.Context(),
+		userID,
+		projectID,
+		secretID,
+		services.RotationPolicyInput{
+			IntervalHours: body.IntervalHours,
+			CronExpr:      body.Cron,
+			Rotator:       body.Rotator,
+			Generate:      body.Generate,
+			WebhookURL:    body.WebhookURL,
+			WebhookSecret: body.WebhookSecret,
+			Enabled:       body.Enabled,
+		},
+	)
+
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(policy)
+}
+
+func (rc *RotationController) GetPolicy(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	policy, err := rc.service.GetPolicy(c.Context(), userID, projectID, secretID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(policy)
+}
+
+func (rc *RotationController) DeletePolicy(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	err := rc.service.DeletePolicy(c.Context(), userID, projectID, secretID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "rotation policy removed"})
+}
+
+func (rc *RotationController) RotateNow(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	secret, err := rc.service.RotateNow(c.Context(), userID, projectID, secretID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(secret)
+}
@@ -1,7 +1,11 @@
 package controllers
 
 import (
+	"errors"
+	"time"
+
 	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -14,14 +18,37 @@ func NewSecretController(service *services.SecretService) *SecretController {
 }
 
 type CreateSecretBody struct {
-	Name  string `json:"name"`
-	Value string `json:"value"`
-	TTL   *int   `json:"ttl"` // number of days and optional
+	Name      string                `json:"name"`
+	Value     string                `json:"value"`
+	Generate  *utils.GeneratePolicy `json:"generate"`   // generate the value server-side instead of sending it
+	TTL       *utils.Duration       `json:"ttl"`        // "15m", "PT15M" or a number of days; optional
+	ExpiresAt *time.Time            `json:"expires_at"` // absolute expiry instead of ttl
+	NotBefore *time.Time            `json:"not_before"` // activation time
+
+	LeaseTTL    *utils.Duration `json:"lease_ttl"` // every read returns a lease of this length
+	LeaseMaxTTL *utils.Duration `json:"lease_max_ttl"`
+
+	MaxReads *int `json:"max_reads"` // destroy the secret after this many reads
+	ReadOnce bool `json:"read_once"` // shorthand for max_reads = 1
+
+	Sensitive bool `json:"sensitive"` // reading needs step-up authentication
+
+	AllowedCIDRs *[]string `json:"allowed_cidrs"` // networks the secret may be read from, within the project's
 }
 
 type UpdateSecretBody struct {
-	Value *string `json:"value"`
-	TTL   *int    `json:"ttl"`
+	Value     *string               `json:"value"`
+	Generate  *utils.GeneratePolicy `json:"generate"`
+	TTL       *utils.Duration       `json:"ttl"`
+	ExpiresAt *time.Time            `json:"expires_at"`
+	NotBefore *time.Time            `json:"not_before"`
+
+	LeaseTTL    *utils.Duration `json:"lease_ttl"` // 0 disables leasing
+	LeaseMaxTTL *utils.Duration `json:"lease_max_ttl"`
+
+	Sensitive *bool `json:"sensitive"`
+
+	AllowedCIDRs *[]string `json:"allowed_cidrs"` // [] clears the allowlist
 }
 
 func (sc *SecretController) CreateSecret(c *fiber.Ctx) error {
@@ -37,8 +64,18 @@ func (sc *SecretController) CreateSecret(c *fiber.Ctx) error {
 	if body.Name == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "name is required"})
 	}
-	if body.Value == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "value is required"})
+	if body.Value == "" && body.Generate == nil {
+		return c.Status(400).JSON(fiber.Map{"error": "value or generate is required"})
+	}
+	if body.Value != "" && body.Generate != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "value and generate are mutually exclusive"})
+	}
+	if body.ReadOnce {
+		if body.MaxReads != nil && *body.MaxReads != 1 {
+			return c.Status(400).JSON(fiber.Map{"error": "read_once conflicts with max_reads"})
+		}
+		one := 1
+		body.MaxReads = &one
 	}
 
 	secret, err := sc.service.CreateSecret(
@@ -47,11 +84,22 @@ func (sc *SecretController) CreateSecret(c *fiber.Ctx) error {
 		projectID,
 		body.Name,
 		body.Value,
-		body.TTL,
+		body.Generate,
+		services.SecretOptions{
+			TTL:         body.TTL.Value(),
+			ExpiresAt:   body.ExpiresAt,
+			NotBefore:   body.NotBefore,
+			LeaseTTL:    body.LeaseTTL.Value(),
+			LeaseMaxTTL: body.LeaseMaxTTL.Value(),
+			MaxReads:    body.MaxReads,
+			Sensitive:   &body.Sensitive,
+
+			AllowedCIDRs: body.AllowedCIDRs,
+		},
 	)
 
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		return changeError(c, err)
 	}
 
 	return c.Status(201).JSON(secret)
@@ -63,7 +111,7 @@ func (sc *SecretController) GetSecret(c *fiber.Ctx) error {
 	projectID := c.Params("projectId")
 	secretID := c.Params("secretId")
 
-	secret, plaintext, err := sc.service.GetSecretByID(
+	secret, plaintext, lease, err := sc.service.GetSecretByID(
 		c.Context(),
 		userID,
 		projectID,
@@ -74,10 +122,30 @@ func (sc *SecretController) GetSecret(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"secret":    secret,
 		"plaintext": plaintext,
-	})
+	}
+	if lease != nil {
+		response["lease_id"] = lease.ID
+		response["lease_duration"] = int(time.Until(lease.ExpiresAt).Seconds())
+		response["lease_expires_at"] = lease.ExpiresAt
+	}
+
+	return c.JSON(response)
+}
+
+func (sc *SecretController) ListSecrets(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+
+	secrets, err := sc.service.ListSecrets(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(secrets)
 }
 
 func (sc *SecretController) UpdateSecret(c *fiber.Ctx) error {
@@ -91,9 +159,13 @@ func (sc *SecretController) UpdateSecret(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid request json"})
 	}
 
-	if body.Value == nil && body.TTL == nil {
+	if body.Value == nil && body.Generate == nil && body.TTL == nil && body.ExpiresAt == nil && body.NotBefore == nil &&
+		body.LeaseTTL == nil && body.LeaseMaxTTL == nil && body.Sensitive == nil {
 		return c.Status(400).JSON(fiber.Map{"error": "nothing to update"})
 	}
+	if body.Value != nil && body.Generate != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "value and generate are mutually exclusive"})
+	}
 
 	updated, err := sc.service.UpdateSecret(
 		c.Context(),
@@ -101,11 +173,21 @@ func (sc *SecretController) UpdateSecret(c *fiber.Ctx) error {
 		projectID,
 		secretID,
 		body.Value,
-		body.TTL,
+		body.Generate,
+		services.SecretOptions{
+			TTL:         body.TTL.Value(),
+			ExpiresAt:   body.ExpiresAt,
+			NotBefore:   body.NotBefore,
+			LeaseTTL:    body.LeaseTTL.Value(),
+			LeaseMaxTTL: body.LeaseMaxTTL.Value(),
+			Sensitive:   body.Sensitive,
+
+			AllowedCIDRs: body.AllowedCIDRs,
+		},
 	)
 
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		return changeError(c, err)
 	}
 
 	return c.JSON(updated)
@@ -125,7 +207,7 @@ func (sc *SecretController) DeleteSecret(c *fiber.Ctx) error {
 	)
 
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		return changeError(c, err)
 	}
 	return c.JSON(fiber.Map{"message": "secret deleted"})
 }
@@ -144,8 +226,62 @@ func (sc *SecretController) RevokeSecret(c *fiber.Ctx) error {
 	)
 
 	if err != nil {
-		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		return changeError(c, err)
 	}
 
 	return c.JSON(fiber.Map{"message": "secret revoked successfully"})
 }
+
+func (sc *SecretController) ListTrash(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+
+	secrets, err := sc.service.ListTrash(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(secrets)
+}
+
+func (sc *SecretController) RestoreSecret(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	secret, err := sc.service.RestoreSecret(c.Context(), userID, projectID, secretID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(secret)
+}
+
+func (sc *SecretController) PurgeSecret(c *fiber.Ctx) error {
+
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	err := sc.service.PurgeSecret(c.Context(), userID, projectID, secretID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{"message": "secret permanently deleted"})
+}
+
+// changeError answers 202 with the change request when a change in a project
+// that requires approval was proposed instead of applied
+func changeError(c *fiber.Ctx, err error) error {
+	var pending *services.PendingApprovalError
+	if errors.As(err, &pending) {
+		return c.Status(202).JSON(fiber.Map{
+			"message":        "change is waiting for approval",
+			"change_request": pending.Request,
+		})
+	}
+	return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+}
@@ -0,0 +1,119 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ServiceAccountController struct {
+	service *services.ServiceAccountService
+}
+
+func NewServiceAccountController(service *services.ServiceAccountService) *ServiceAccountController {
+	return &ServiceAccountController{service: service}
+}
+
+type ServiceAccountBody struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	PathPrefix  string  `json:"path_prefix"` // limit the account to secrets whose name starts with this
+
+	AllowedCIDRs []string `json:"allowed_cidrs"` // networks the account may read secrets from; empty allows any address
+}
+
+type ServiceTokenBody struct {
+	Label        string          `json:"label"`
+	Scopes       []string        `json:"scopes"`        // default read and list
+	AllowedCIDRs []string        `json:"allowed_cidrs"` // empty allows any address
+	TTL          *utils.Duration `json:"ttl"`           // default 30 days
+}
+
+func (sc *ServiceAccountController) CreateServiceAccount(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body ServiceAccountBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	account, err := sc.service.CreateServiceAccount(c.Context(), userID, projectID, body.Name, body.Description, body.PathPrefix, body.AllowedCIDRs)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.Status(201).JSON(account)
+}
+
+func (sc *ServiceAccountController) ListServiceAccounts(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	accounts, err := sc.service.ListServiceAccounts(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(accounts)
+}
+
+func (sc *ServiceAccountController) DeleteServiceAccount(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	accountID := c.Params("saId")
+
+	if err := sc.service.DeleteServiceAccount(c.Context(), userID, projectID, accountID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "service account deleted"})
+}
+
+func (sc *ServiceAccountController) CreateToken(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	accountID := c.Params("saId")
+
+	var body ServiceTokenBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	token, plaintext, err := sc.service.CreateToken(c.Context(), userID, projectID, accountID, services.ServiceTokenInput{
+		Label:        body.Label,
+		Scopes:       body.Scopes,
+		AllowedCIDRs: body.AllowedCIDRs,
+		TTL:          body.TTL.Value(),
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// the token itself is only returned once, on creation
+	return c.Status(201).JSON(fiber.Map{
+		"token":      plaintext,
+		"token_info": token,
+	})
+}
+
+func (sc *ServiceAccountController) ListTokens(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	accountID := c.Params("saId")
+
+	tokens, err := sc.service.ListTokens(c.Context(), userID, projectID, accountID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(tokens)
+}
+
+func (sc *ServiceAccountController) RevokeToken(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	accountID := c.Params("saId")
+	tokenID := c.Params("tokenId")
+
+	if err := sc.service.RevokeToken(c.Context(), userID, projectID, accountID, tokenID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "token revoked"})
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"os"
+	"strings"
+
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type ShareController struct {
+	service *services.ShareService
+}
+
+func NewShareController(service *services.ShareService) *ShareController {
+	return &ShareController{service: service}
+}
+
+type ShareBody struct {
+	Value      *string         `json:"value"`     // ad-hoc value
+	SecretID   *string         `json:"secret_id"` // or snapshot an existing secret
+	Label      string          `json:"label"`
+	TTL        *utils.Duration `json:"ttl"`       // default 24h
+	MaxViews   *int            `json:"max_views"` // default 1
+	Passphrase string          `json:"passphrase"`
+}
+
+type ViewShareBody struct {
+	Key        string `json:"key"` // the fragment after "#" in the link
+	Passphrase string `json:"passphrase"`
+}
+
+func (sc *ShareController) CreateShare(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body ShareBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+
+	share, fragment, err := sc.service.CreateShare(c.Context(), userID, projectID, services.ShareInput{
+		Value:      body.Value,
+		SecretID:   body.SecretID,
+		Label:      body.Label,
+		TTL:        body.TTL.Value(),
+		MaxViews:   body.MaxViews,
+		Passphrase: body.Passphrase,
+	})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// the key is only returned once, on creation
+	response := fiber.Map{
+		"share": share,
+		"key":   fragment,
+	}
+	if base := os.Getenv("SHARE_BASE_URL"); base != "" {
+		response["url"] = strings.TrimRight(base, "/") + "/" + share.ID.String() + "#" + fragment
+	}
+	return c.Status(201).JSON(response)
+}
+
+func (sc *ShareController) ListShares(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	shares, err := sc.service.ListShares(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(shares)
+}
+
+func (sc *ShareController) RevokeShare(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	shareID := c.Params("shareId")
+
+	if err := sc.service.RevokeShare(c.Context(), userID, projectID, shareID); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "share revoked"})
+}
+
+func (sc *ShareController) ViewShare(c *fiber.Ctx) error {
+	shareID := c.Params("shareId")
+
+	var body ViewShareBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.Key == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "key is required"})
+	}
+
+	plaintext, share, err := sc.service.ViewShare(c.Context(), shareID, body.Key, body.Passphrase)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{
+		"label":           share.Label,
+		"plaintext":       plaintext,
+		"views_remaining": share.MaxViews - share.Views,
+		"expires_at":      share.ExpiresAt,
+	})
+}
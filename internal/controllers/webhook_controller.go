@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WebhookController struct {
+	service *services.WebhookService
+}
+
+func NewWebhookController(service *services.WebhookService) *WebhookController {
+	return &WebhookController{service: service}
+}
+
+type WebhookBody struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"` // e.g. ["secret.created", "project.*"], empty for all events
+}
+
+func (wc *WebhookController) CreateWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	var body WebhookBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.URL == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "url is required"})
+	}
+
+	sub, signingSecret, err := wc.service.CreateSubscription(c.Context(), userID, projectID, body.URL, body.Events)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	// the signing secret is only returned once, on creation
+	return c.Status(201).JSON(fiber.Map{
+		"webhook":        sub,
+		"signing_secret": signingSecret,
+	})
+}
+
+func (wc *WebhookController) ListWebhooks(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+
+	subs, err := wc.service.ListSubscriptions(c.Context(), userID, projectID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(subs)
+}
+
+func (wc *WebhookController) DeleteWebhook(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	webhookID := c.Params("webhookId")
+
+	err := wc.service.DeleteSubscription(c.Context(), userID, projectID, webhookID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "webhook deleted"})
+}
+
+func (wc *WebhookController) ListDeliveries(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	status := c.Query("status") // "dead" for the dead-letter view
+
+	deliveries, err := wc.service.ListDeliveries(c.Context(), userID, projectID, status)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(deliveries)
+}
+
+func (wc *WebhookController) RetryDelivery(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("id")
+	deliveryID := c.Params("deliveryId")
+
+	delivery, err := wc.service.RetryDelivery(c.Context(), userID, projectID, deliveryID)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(delivery)
+}
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+type WrappingController struct {
+	service *services.WrappingService
+}
+
+func NewWrappingController(service *services.WrappingService) *WrappingController {
+	return &WrappingController{service: service}
+}
+
+type WrapBody struct {
+	TTL *utils.Duration `json:"ttl"` // how long the token can be unwrapped, default 5m
+}
+
+type UnwrapBody struct {
+	Token string `json:"token"`
+}
+
+func (wc *WrappingController) WrapSecret(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	projectID := c.Params("projectId")
+	secretID := c.Params("secretId")
+
+	var body WrapBody
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&body); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+		}
+	}
+
+	wrapped, token, err := wc.service.Wrap(c.Context(), userID, projectID, secretID, body.TTL.Value())
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"token":      token,
+		"expires_at": wrapped.ExpiresAt,
+	})
+}
+
+func (wc *WrappingController) Unwrap(c *fiber.Ctx) error {
+	var body UnwrapBody
+	if err := c.BodyParser(&body); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid json"})
+	}
+	if body.Token == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "token is required"})
+	}
+
+	secret, err := wc.service.Unwrap(c.Context(), body.Token)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	return c.JSON(secret)
+}
@@ -37,6 +37,7 @@ func ConnectDB() {
 	log.Println("Connected to PostgreSQL")
 
 	createTables(ctx)
+	migrateTables(ctx)
 
 }
 
@@ -69,4 +70,211 @@ func createTables(ctx context.Context) {
 		log.Fatal("Error creating audit logs table:", err)
 	}
 
+	_, err = DB.NewCreateTable().
+		Model((*models.ProjectMember)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating project members table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.RotationPolicy)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating rotation policies table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.NotificationChannel)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating notification channels table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.NotificationRecord)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating notification records table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.WebhookSubscription)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating webhook subscriptions table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.WebhookDelivery)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating webhook deliveries table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.Lease)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating leases table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.WrappedToken)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating wrapped tokens table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.Share)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating shares table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.Policy)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating policies table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.PolicyAttachment)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating policy attachments table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.Organization)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating organizations table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.Team)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating teams table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.ServiceAccount)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating service accounts table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.ServiceToken)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating service tokens table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.AppRole)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating approles table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.AppRoleSecretID)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating approle secret ids table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.MFAFactor)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating mfa factors table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.GatewayNonce)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating gateway nonces table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.ChangeRequest)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating change requests table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.ChangeRequestComment)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating change request comments table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.AccessGrant)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating access grants table:", err)
+	}
+
+	_, err = DB.NewCreateTable().
+		Model((*models.BreakGlassSession)(nil)).
+		IfNotExists().
+		Exec(ctx)
+
+	if err != nil {
+		log.Fatal("Error creating break glass sessions table:", err)
+	}
+
 }
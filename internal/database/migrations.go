@@ -0,0 +1,98 @@
+package database
+
+import (
+	"context"
+	"log"
+)
+
+// migrations bring tables created by an earlier version up to the current
+// models, since createTables leaves existing tables alone. They run in order
+// on every start, so each one must be safe to run again.
+var migrations = []struct {
+	name  string
+	query string
+}{
+	{
+		// event filters were a text array, like the other list columns they are jsonb now
+		name: "webhook subscription events",
+		query: `DO $$ BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'webhook_subscriptions' AND column_name = 'events' AND data_type = 'ARRAY') THEN
+				ALTER TABLE webhook_subscriptions ALTER COLUMN events TYPE jsonb USING to_jsonb(events);
+			END IF;
+		END $$`,
+	},
+	{
+		name:  "project ttl bounds",
+		query: `ALTER TABLE projects ADD COLUMN IF NOT EXISTS min_ttl_seconds BIGINT, ADD COLUMN IF NOT EXISTS max_ttl_seconds BIGINT`,
+	},
+	{
+		// ttl used to be a number of days
+		name: "secret ttl in seconds",
+		query: `DO $$ BEGIN
+			ALTER TABLE secrets ADD COLUMN IF NOT EXISTS ttl_seconds BIGINT, ADD COLUMN IF NOT EXISTS not_before TIMESTAMPTZ;
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'secrets' AND column_name = 'ttl') THEN
+				UPDATE secrets SET ttl_seconds = ttl::bigint * 86400 WHERE ttl IS NOT NULL AND ttl_seconds IS NULL;
+				ALTER TABLE secrets DROP COLUMN ttl;
+			END IF;
+		END $$`,
+	},
+	{
+		name:  "secret leases",
+		query: `ALTER TABLE secrets ADD COLUMN IF NOT EXISTS lease_ttl_seconds BIGINT, ADD COLUMN IF NOT EXISTS lease_max_ttl_seconds BIGINT`,
+	},
+	{
+		name:  "limited-read secrets",
+		query: `ALTER TABLE secrets ADD COLUMN IF NOT EXISTS max_reads BIGINT, ADD COLUMN IF NOT EXISTS reads_remaining BIGINT`,
+	},
+	{
+		name:  "secrets deleted with their project",
+		query: `ALTER TABLE secrets ADD COLUMN IF NOT EXISTS deleted_with_project BOOLEAN NOT NULL DEFAULT false`,
+	},
+	{
+		// existing projects stay owned by their creator
+		name:  "team and org owned projects",
+		query: `ALTER TABLE projects ADD COLUMN IF NOT EXISTS owner_type VARCHAR NOT NULL DEFAULT 'user', ADD COLUMN IF NOT EXISTS owner_id UUID, ADD COLUMN IF NOT EXISTS org_id UUID`,
+	},
+	{
+		name:  "sensitive secrets",
+		query: `ALTER TABLE secrets ADD COLUMN IF NOT EXISTS sensitive BOOLEAN NOT NULL DEFAULT false`,
+	},
+	{
+		name:  "project approval setting",
+		query: `ALTER TABLE projects ADD COLUMN IF NOT EXISTS require_approval BOOLEAN NOT NULL DEFAULT false`,
+	},
+	{
+		name:  "audit access grants",
+		query: `ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS grant_id UUID`,
+	},
+	{
+		name:  "audit break-glass flag",
+		query: `ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS break_glass BOOLEAN NOT NULL DEFAULT false`,
+	},
+	{
+		name:  "audit source address",
+		query: `ALTER TABLE audit_logs ADD COLUMN IF NOT EXISTS source_ip VARCHAR`,
+	},
+	{
+		name:  "project network allowlist",
+		query: `ALTER TABLE projects ADD COLUMN IF NOT EXISTS allowed_cidrs JSONB`,
+	},
+	{
+		name:  "secret network allowlist",
+		query: `ALTER TABLE secrets ADD COLUMN IF NOT EXISTS allowed_cidrs JSONB`,
+	},
+	{
+		name:  "service account network allowlist",
+		query: `ALTER TABLE service_accounts ADD COLUMN IF NOT EXISTS allowed_cidrs JSONB`,
+	},
+}
+
+func migrateTables(ctx context.Context) {
+	for _, migration := range migrations {
+		if _, err := DB.ExecContext(ctx, migration.query); err != nil {
+			log.Fatal("Error migrating "+migration.name+":", err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package identity
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+// Identity is the authenticated caller of a request
+type Identity struct {
+	UserID string
+	Email  string
+	Groups []string // from the gateway, used to match group policies
+
+	Orgs      []string // slugs of the organizations the user belongs to
+	OrgAdmins []string // slugs of the organizations the user administers
+	Teams     []string // "<orgSlug>/<teamSlug>" of the user's teams
+
+	// from an identity provider JWT: the scopes it grants and the projects it
+	// is limited to; nil when the token has no such claim
+	Scopes     []string
+	ProjectIDs []string
+
+	// from an identity provider JWT: how and when the user signed in (amr and
+	// auth_time), used for step-up on sensitive secrets
+	AuthMethods []string
+	AuthTime    *time.Time
+
+	// one-time code sent in the X-Cryptex-TOTP header to step up
+	TOTPCode string
+
+	// the SAN or CN of the client certificate that authenticated the request
+	Certificate string
+
+	// client address of the request, read from the proxy header when it
+	// comes through one of the TRUSTED_PROXIES
+	IP string
+
+	// break-glass sessions the request was authorized under, by project ID,
+	// so its audit entries are flagged
+	BreakGlass map[string]uuid.UUID
+
+	// set when a machine token authenticated the request; UserID is then the service account ID
+	ServiceAccount *ServiceAccount
+}
+
+// ServiceAccount is what a machine token may reach: one project and the
+// secrets under PathPrefix in it, or the Paths globs of an AppRole login, and
+// only with the Scopes of the token
+type ServiceAccount struct {
+	ID         string   `json:"id"` // service account or AppRole ID
+	ProjectID  string   `json:"project_id,omitempty"`
+	PathPrefix string   `json:"path_prefix,omitempty"`
+	Paths      []string `json:"paths,omitempty"`
+	TokenID    string   `json:"token_id"`
+	Scopes     []string `json:"scopes"`
+
+	AllowedCIDRs []string `json:"allowed_cidrs,omitempty"` // where the service account may read secrets from
+}
+
+type localsKey struct{}
+
+// Attach stores the caller on the request, with its address and the step-up
+// code it sent if any. Fiber locals are also the values of c.Context(), so
+// services can read it back with FromContext.
+func Attach(c *fiber.Ctx, id *Identity) {
+	id.TOTPCode = c.Get("X-Cryptex-TOTP")
+	id.IP = c.IP()
+	c.Locals(localsKey{}, id)
+}
+
+// FromContext returns the caller of the request ctx belongs to, or nil for
+// background jobs
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(localsKey{}).(*Identity)
+	return id
+}
+
+// MarkBreakGlass records that the request relied on a break-glass session in
+// the project. It does nothing on a nil identity.
+func (id *Identity) MarkBreakGlass(projectID string, session uuid.UUID) {
+	if id == nil {
+		return
+	}
+	if id.BreakGlass == nil {
+		id.BreakGlass = map[string]uuid.UUID{}
+	}
+	id.BreakGlass[projectID] = session
+}
+
+// CertificateUserID is the user ID of a client certificate identity: the
+// UUIDv5 of its name in the URL namespace
+func CertificateUserID(name string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String()
+}
+
+// SubjectUserID is the user ID of an identity provider subject: the subject
+// itself when it is a UUID, else the UUIDv5 of "<issuer>#<subject>" in the
+// URL namespace, since most providers use other formats (auth0|123)
+func SubjectUserID(issuer string, subject string) string {
+	if id, err := uuid.Parse(subject); err == nil {
+		return id.String()
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(issuer+"#"+subject)).String()
+}
+
+// ParseList splits a comma separated header value, dropping empty entries
+func ParseList(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
+
+// ParseOrgs splits an X-User-Orgs value like "acme:admin, globex" into the
+// orgs the user belongs to and the ones they administer
+func ParseOrgs(value string) (orgs []string, admins []string) {
+	for _, entry := range ParseList(value) {
+		slug, role, _ := strings.Cut(entry, ":")
+		slug = strings.TrimSpace(slug)
+		if slug == "" {
+			continue
+		}
+		orgs = append(orgs, slug)
+		if strings.TrimSpace(role) == "admin" {
+			admins = append(admins, slug)
+		}
+	}
+	return orgs, admins
+}
+
+// IsAdmin reports whether the user is a service administrator (ADMIN_USER_IDS)
+func IsAdmin(userID string) bool {
+	for _, admin := range ParseList(os.Getenv("ADMIN_USER_IDS")) {
+		if admin == userID {
+			return true
+		}
+	}
+	return false
+}
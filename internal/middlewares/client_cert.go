@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"crypto/x509"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/gofiber/fiber/v2"
+)
+
+// ClientCertAuth authenticates requests that presented a verified client
+// certificate, and hands the others to fallback (nil rejects them). Requests
+// with X-Gateway-Source also go to fallback, so a gateway that connects with
+// its own certificate still passes on the user's identity.
+//
+// The certificate's first URI SAN (such as a SPIFFE ID), else its first DNS
+// SAN, else its CN names the caller. Its user ID is the UUIDv5 of that name in
+// the URL namespace, so it can be made a project member, and policies can be
+// attached to the name with the certificate principal.
+func ClientCertAuth(fallback fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		state := c.Context().TLSConnectionState()
+		if state == nil || len(state.VerifiedChains) == 0 || c.Get("X-Gateway-Source") != "" {
+			if fallback == nil {
+				return c.Status(fiber.StatusUnauthorized).
+					JSON(fiber.Map{"error": "missing auth"})
+			}
+			return fallback(c)
+		}
+
+		name := certificateName(state.VerifiedChains[0][0])
+		if name == "" {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"error": "client certificate has no SAN or CN"})
+		}
+
+		userID := identity.CertificateUserID(name)
+		c.Locals("userId", userID)
+		identity.Attach(c, &identity.Identity{
+			UserID:      userID,
+			Certificate: name,
+		})
+		return c.Next()
+	}
+}
+
+func certificateName(cert *x509.Certificate) string {
+	if len(cert.URIs) > 0 {
+		return cert.URIs[0].String()
+	}
+	if len(cert.DNSNames) > 0 {
+		return cert.DNSNames[0]
+	}
+	return cert.Subject.CommonName
+}
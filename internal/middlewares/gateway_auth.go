@@ -1,8 +1,37 @@
 package middlewares
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// NonceStore remembers the nonces of signed gateway requests. It is shared by
+// every instance of the service, so a request cannot be replayed against
+// another replica.
+type NonceStore interface {
+	RecordNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+	DeleteExpiredNonces(ctx context.Context) error
+}
+
+// how often expired nonces are removed from the store
+const noncePruneInterval = time.Minute
+
+// GatewayAuth trusts the identity headers of the gateway. With keys, every
+// request must carry an HMAC signature over those headers, the method, path,
+// timestamp and nonce made with one of the keys, a timestamp within maxSkew
+// and a nonce not seen before. Nil keys accept unsigned headers, which is
+// only safe when nothing but the gateway can reach the service.
+func GatewayAuth(keys map[string][]byte, maxSkew time.Duration, store NonceStore) fiber.Handler {
+	nonces := &nonceChecker{store: store}
 
-func GatewayAuth() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		gatewayHeader := c.Get("X-Gateway-Source")
 		if gatewayHeader != "cryptex-gateway" {
@@ -10,6 +39,13 @@ func GatewayAuth() fiber.Handler {
 				JSON(fiber.Map{"error": "direct access forbidden"})
 		}
 
+		if keys != nil {
+			if err := verifyGateway(c, keys, maxSkew, nonces); err != nil {
+				return c.Status(fiber.StatusUnauthorized).
+					JSON(fiber.Map{"error": err.Error()})
+			}
+		}
+
 		userId := c.Get("X-User-Id")
 		email := c.Get("X-User-Email")
 
@@ -20,7 +56,87 @@ func GatewayAuth() fiber.Handler {
 
 		c.Locals("userId", userId)
 		c.Locals("email", email)
+		orgs, orgAdmins := identity.ParseOrgs(c.Get("X-User-Orgs"))
+		identity.Attach(c, &identity.Identity{
+			UserID:    userId,
+			Email:     email,
+			Groups:    identity.ParseList(c.Get("X-User-Groups")),
+			Orgs:      orgs,
+			OrgAdmins: orgAdmins,
+			Teams:     identity.ParseList(c.Get("X-User-Teams")),
+		})
 
 		return c.Next()
 	}
 }
+
+func verifyGateway(c *fiber.Ctx, keys map[string][]byte, maxSkew time.Duration, nonces *nonceChecker) error {
+	keyID := c.Get(utils.GatewayKeyIDHeader)
+	timestamp := c.Get(utils.GatewayTimestampHeader)
+	nonce := c.Get(utils.GatewayNonceHeader)
+	signature := c.Get(utils.GatewaySignatureHeader)
+	if keyID == "" || timestamp == "" || nonce == "" || signature == "" {
+		return errors.New("missing gateway signature")
+	}
+
+	key, ok := keys[keyID]
+	if !ok {
+		return errors.New("unknown gateway key " + keyID)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("invalid gateway timestamp")
+	}
+	skew := time.Since(time.Unix(seconds, 0))
+	if skew > maxSkew || skew < -maxSkew {
+		return errors.New("gateway timestamp outside the allowed window")
+	}
+
+	values := make([]string, 0, len(utils.GatewayIdentityHeaders))
+	for _, header := range utils.GatewayIdentityHeaders {
+		values = append(values, c.Get(header))
+	}
+	stringToSign := utils.GatewayStringToSign(c.Method(), c.OriginalURL(), timestamp, nonce, values)
+	if !utils.VerifyGatewaySignature(key, stringToSign, signature) {
+		return errors.New("invalid gateway signature")
+	}
+
+	// checked after the signature so forged requests cannot burn nonces;
+	// a nonce only needs remembering while its timestamp is acceptable
+	fresh, err := nonces.add(c.Context(), keyID+":"+nonce, time.Unix(seconds, 0).Add(maxSkew))
+	if err != nil {
+		log.Printf("[AUTH ERROR] cannot record gateway nonce: %v", err)
+		return errors.New("cannot verify gateway request")
+	}
+	if !fresh {
+		return errors.New("replayed gateway request")
+	}
+	return nil
+}
+
+// nonceChecker records nonces in the store and removes expired ones from
+// time to time
+type nonceChecker struct {
+	store    NonceStore
+	mu       sync.Mutex
+	prunedAt time.Time
+}
+
+// add records a nonce, reporting false when it was already seen
+func (n *nonceChecker) add(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	n.mu.Lock()
+	prune := time.Since(n.prunedAt) > noncePruneInterval
+	if prune {
+		n.prunedAt = time.Now()
+	}
+	n.mu.Unlock()
+
+	if prune {
+		if err := n.store.DeleteExpiredNonces(ctx); err != nil {
+			// expired entries are taken over anyway, pruning only keeps the table small
+			log.Printf("[AUTH ERROR] cannot prune gateway nonces: %v", err)
+		}
+	}
+	return n.store.RecordNonce(ctx, nonce, expiresAt)
+}
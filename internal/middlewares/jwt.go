@@ -1,47 +1,129 @@
 package middlewares
 
 import (
+	"context"
 	"strings"
+	"time"
 
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func JWTAuth(secret []byte) fiber.Handler {
+// JWTAuth authenticates requests carrying a JWT of an external identity
+// provider, so the service can run without the gateway in front of it. The
+// sub claim is the user (see identity.SubjectUserID); email, groups, orgs and
+// teams are used like the gateway headers.
+// The scope (or scp) and project_ids claims, when present, narrow what the
+// token can do; see RequireScope. amr and auth_time count for step-up.
+func JWTAuth(verifier *utils.JWTVerifier) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-
-		//extracting auth header
-		authHeader := c.Get("Authorization")
-		if authHeader == "" {
+		tokenStr, ok := bearerToken(c)
+		if !ok {
 			return c.Status(401).JSON(fiber.Map{"error": "missing auth"})
 		}
-		//bearer token extraction
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
-			return c.Status(401).JSON(fiber.Map{"error": "invalid auth header"})
+
+		claims, err := verifier.Parse(tokenStr)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
 		}
-		tokenStr := parts[1]
 
-		token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fiber.NewError(fiber.StatusUnauthorized, "Unexpected signing method")
-			}
-			return secret, nil
+		var authTime *time.Time
+		if seconds, ok := claims["auth_time"].(float64); ok {
+			t := time.Unix(int64(seconds), 0)
+			authTime = &t
+		}
+
+		issuer, _ := claims["iss"].(string)
+		userID := identity.SubjectUserID(issuer, claims["sub"].(string))
+		email, _ := claims["email"].(string)
+		orgs, orgAdmins := identity.ParseOrgs(strings.Join(claimList(claims["orgs"]), ","))
+		c.Locals("userId", userID)
+		c.Locals("email", email)
+		c.Locals("claims", claims)
+		identity.Attach(c, &identity.Identity{
+			UserID: userID,
+			Email:  email,
+			Groups: claimList(claims["groups"]),
+
+			Orgs:      orgs,
+			OrgAdmins: orgAdmins,
+			Teams:     claimList(claims["teams"]),
+
+			Scopes:     tokenScopes(claims),
+			ProjectIDs: claimList(claims["project_ids"]),
+
+			AuthMethods: claimList(claims["amr"]),
+			AuthTime:    authTime,
 		})
-		if err != nil || !token.Valid {
-			return c.Status(401).JSON(fiber.Map{"error": "invalid token"})
+		return c.Next()
+	}
+}
+
+// tokenScopes reads the OAuth scope claim (space separated) or the scp claim
+// some providers use instead
+func tokenScopes(claims jwt.MapClaims) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return append([]string{}, strings.Fields(scope)...)
+	}
+	if scp, ok := claims["scp"].(string); ok {
+		return append([]string{}, strings.Fields(scp)...)
+	}
+	return claimList(claims["scp"])
+}
+
+// claimList reads a claim holding either a list of strings or a comma
+// separated string. It returns nil only when the claim is missing.
+func claimList(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return append([]string{}, identity.ParseList(value)...)
+	case []interface{}:
+		out := []string{}
+		for _, entry := range value {
+			if s, ok := entry.(string); ok && s != "" {
+				out = append(out, s)
+			}
 		}
+		return out
+	}
+	return nil
+}
+
+// AppRoleVerifier checks that the AppRole a token was issued for still exists
+type AppRoleVerifier interface {
+	VerifyRole(ctx context.Context, roleID string) error
+}
 
-		claims := token.Claims.(jwt.MapClaims)
+// AppRoleAuth authenticates requests carrying a token issued by this service
+// at /api/auth/approle/login. The token acts as its role, limited to the
+// role's paths and scopes, and stops working when the role is deleted.
+func AppRoleAuth(key []byte, roles AppRoleVerifier) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		tokenStr, ok := bearerToken(c)
+		if !ok {
+			return c.Status(401).JSON(fiber.Map{"error": "missing auth"})
+		}
 
-		userID, ok := claims["sub"].(string)
-		if !ok || userID == "" {
-			return c.Status(401).JSON(
-				fiber.Map{"error": "Token does not contain valid user ID"},
-			)
+		claims, err := utils.ParseAppRoleToken(key, tokenStr)
+		if err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": "invalid token"})
 		}
-		c.Locals("userId", userID)
-		c.Locals("claims", claims)
+		if err := roles.VerifyRole(c.Context(), claims.Subject); err != nil {
+			return c.Status(401).JSON(fiber.Map{"error": err.Error()})
+		}
+
+		c.Locals("userId", claims.Subject)
+		identity.Attach(c, &identity.Identity{
+			UserID: claims.Subject,
+			ServiceAccount: &identity.ServiceAccount{
+				ID:      claims.Subject,
+				Paths:   claims.Paths,
+				TokenID: claims.ID,
+				Scopes:  claims.Scopes,
+			},
+		})
 		return c.Next()
 	}
 }
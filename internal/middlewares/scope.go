@@ -0,0 +1,47 @@
+package middlewares
+
+import (
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/gofiber/fiber/v2"
+)
+
+// scopes an identity provider token can carry
+const (
+	ScopeSecretsRead   = "secrets:read"
+	ScopeSecretsWrite  = "secrets:write"
+	ScopeProjectsRead  = "projects:read"
+	ScopeProjectsAdmin = "projects:admin"
+)
+
+// scopes that include another one
+var impliedScopes = map[string][]string{
+	ScopeProjectsRead: {ScopeProjectsAdmin},
+}
+
+// RequireScope rejects requests whose JWT has a scope claim without scope.
+// Tokens without a scope claim, gateway requests and machine tokens (which
+// carry their own scopes) pass; the user's roles still apply after this.
+func RequireScope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		id := identity.FromContext(c.Context())
+		if id == nil || id.Scopes == nil || hasScope(id.Scopes, scope) {
+			return c.Next()
+		}
+		return c.Status(fiber.StatusForbidden).
+			JSON(fiber.Map{"error": "insufficient scope: " + scope + " is required"})
+	}
+}
+
+func hasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+		for _, implied := range impliedScopes[scope] {
+			if granted == implied {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package middlewares
+
+import (
+	"context"
+	"strings"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// TokenVerifier resolves a machine token presented from an address to the identity it acts as
+type TokenVerifier interface {
+	VerifyToken(ctx context.Context, token string, ip string) (*identity.Identity, error)
+}
+
+// TokenAuth authenticates requests carrying "Authorization: Bearer <token>"
+// with a token that starts with prefix, without going through the gateway
+func TokenAuth(verifier TokenVerifier, prefix string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		token, ok := bearerToken(c)
+		if !ok || !strings.HasPrefix(token, prefix) {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"error": "missing service token"})
+		}
+
+		id, err := verifier.VerifyToken(c.Context(), token, c.IP())
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"error": err.Error()})
+		}
+
+		c.Locals("userId", id.UserID)
+		identity.Attach(c, id)
+		return c.Next()
+	}
+}
+
+// BearerAuth authenticates the bearer tokens Match accepts
+type BearerAuth struct {
+	Match   func(token string) bool
+	Handler fiber.Handler
+}
+
+// Authenticate hands a request with a bearer token to the first BearerAuth
+// that accepts the token, and any other request to fallback. A nil fallback
+// rejects them.
+func Authenticate(fallback fiber.Handler, bearers ...BearerAuth) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if token, ok := bearerToken(c); ok {
+			for _, bearer := range bearers {
+				if bearer.Match(token) {
+					return bearer.Handler(c)
+				}
+			}
+		}
+		if fallback == nil {
+			return c.Status(fiber.StatusUnauthorized).
+				JSON(fiber.Map{"error": "missing auth"})
+		}
+		return fallback(c)
+	}
+}
+
+// HasPrefix matches bearer tokens starting with prefix
+func HasPrefix(prefix string) func(string) bool {
+	return func(token string) bool {
+		return strings.HasPrefix(token, prefix)
+	}
+}
+
+// IssuedBy matches JWTs whose iss is issuer. The claim is read unverified,
+// only to pick the handler that will verify the token.
+func IssuedBy(issuer string) func(string) bool {
+	return func(token string) bool {
+		return strings.Count(token, ".") == 2 && utils.TokenIssuer(token) == issuer
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(c *fiber.Ctx) (string, bool) {
+	parts := strings.SplitN(c.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" || parts[1] == "" {
+		return "", false
+	}
+	return strings.TrimSpace(parts[1]), true
+}
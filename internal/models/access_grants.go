@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// AccessGrant is temporary read access to a project or some of its secrets,
+// requested with a justification instead of becoming a member
+type AccessGrant struct {
+	bun.BaseModel `bun:"table:access_grants,alias:access_grant"`
+
+	ID            uuid.UUID `bun:"grant_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID     uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	UserID        uuid.UUID `bun:"user_id,type:uuid,notnull"` // who gets the access
+	Path          string    `bun:"path,notnull,default:'**'"` // secret name glob within the project, ** is the whole project
+	Justification string    `bun:"justification,notnull"`
+	Duration      int64     `bun:"duration_seconds,notnull"`         // how long the grant lasts once approved
+	Status        string    `bun:"status,notnull,default:'pending'"` // pending, active, rejected, revoked or expired
+
+	AutoApproved bool       `bun:"auto_approved,notnull,default:false"` // approved by an auto-grant policy rule
+	DecidedBy    *uuid.UUID `bun:"decided_by,type:uuid,nullzero"`
+	DecidedAt    *time.Time `bun:"decided_at,nullzero"`
+	ExpiresAt    *time.Time `bun:"expires_at,nullzero"` // set on approval
+	RevokedBy    *uuid.UUID `bun:"revoked_by,type:uuid,nullzero"`
+	RevokedAt    *time.Time `bun:"revoked_at,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// AppRole lets a workload log in with its role ID and a secret ID and get a
+// short-lived token limited to Paths and Scopes
+type AppRole struct {
+	bun.BaseModel `bun:"table:approles"`
+
+	ID     uuid.UUID `bun:"role_id,pk,type:uuid,default:gen_random_uuid()"` // the public role ID
+	Name   string    `bun:"role_name,notnull,unique"`
+	Paths  []string  `bun:"paths,type:jsonb,notnull"`  // "<projectId>/<secret glob>"
+	Scopes []string  `bun:"scopes,type:jsonb,notnull"` // read, list, create, update, delete, revoke
+
+	TokenTTL        int64  `bun:"token_ttl_seconds,notnull"`
+	SecretIDTTL     *int64 `bun:"secret_id_ttl_seconds,nullzero"` // nil never expires
+	SecretIDNumUses *int   `bun:"secret_id_num_uses,nullzero"`    // nil is unlimited
+
+	CreatedBy uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
+
+// AppRoleSecretID is a login credential of an AppRole. Only its hash is
+// stored; the accessor identifies it for listing and destroying.
+type AppRoleSecretID struct {
+	bun.BaseModel `bun:"table:approle_secret_ids"`
+
+	Accessor      uuid.UUID `bun:"accessor,pk,type:uuid,default:gen_random_uuid()"`
+	RoleID        uuid.UUID `bun:"role_id,type:uuid,notnull"`
+	SecretHash    string    `bun:"secret_hash,notnull,unique" json:"-"`
+	UsesRemaining *int      `bun:"uses_remaining,nullzero"` // nil is unlimited
+	CreatedBy     uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+
+	ExpiresAt  *time.Time `bun:"expires_at,nullzero"`
+	LastUsedAt *time.Time `bun:"last_used_at,nullzero"`
+	CreatedAt  time.Time  `bun:"created_at,default:current_timestamp"`
+}
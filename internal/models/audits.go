@@ -14,6 +14,10 @@ type AuditLog struct {
 	UserID    uuid.UUID `bun:"user_id,type:uuid,nullzero"` // can be null for system events
 	ProjectID uuid.UUID `bun:"project_id,type:uuid,nullzero"`
 	SecretID  uuid.UUID `bun:"secret_id,type:uuid,nullzero"`
+	GrantID   uuid.UUID `bun:"grant_id,type:uuid,nullzero"` // access grant the action relied on
+
+	BreakGlass bool   `bun:"break_glass,notnull,default:false"` // authorized by a break-glass session of the user on the project
+	SourceIP   string `bun:"source_ip,nullzero"`                // client address of the request, empty for background jobs
 
 	Action  string  `bun:"action,notnull"`
 	Message *string `bun:"message,nullzero"`
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// BreakGlassSession is emergency access of a service administrator to a
+// project whose owners cannot be reached. Every audit entry of the
+// administrator on the project during the session carries the break-glass flag.
+type BreakGlassSession struct {
+	bun.BaseModel `bun:"table:break_glass_sessions,alias:break_glass_session"`
+
+	ID        uuid.UUID `bun:"session_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	UserID    uuid.UUID `bun:"user_id,type:uuid,notnull"`
+	Reason    string    `bun:"reason,notnull"`
+
+	ExpiresAt time.Time  `bun:"expires_at,notnull"`
+	EndedAt   *time.Time `bun:"ended_at,nullzero"` // set when the session is closed before it expires
+	EndedBy   *uuid.UUID `bun:"ended_by,type:uuid,nullzero"`
+	CreatedAt time.Time  `bun:"created_at,default:current_timestamp"`
+}
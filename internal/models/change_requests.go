@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ChangeRequest is a proposed secret change in a project that requires
+// approval. It is applied once a different member with approve rights
+// approves it.
+type ChangeRequest struct {
+	bun.BaseModel `bun:"table:change_requests,alias:change_request"`
+
+	ID         uuid.UUID  `bun:"cr_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID  uuid.UUID  `bun:"project_id,type:uuid,notnull"`
+	SecretID   *uuid.UUID `bun:"secret_id,type:uuid,nullzero"` // nil for a create until it is applied
+	SecretName string     `bun:"secret_name,notnull"`
+	Action     string     `bun:"action,notnull"` // create, update, revoke or delete
+
+	Payload     *string `bun:"payload,nullzero" json:"-"`        // encrypted proposed value and options
+	BaseVersion *int    `bun:"base_version,nullzero"`            // secret version the change was proposed against
+	Status      string  `bun:"status,notnull,default:'pending'"` // pending, approved, applied, rejected, cancelled, expired or failed
+
+	RequestedBy uuid.UUID  `bun:"requested_by,type:uuid,notnull"`
+	DecidedBy   *uuid.UUID `bun:"decided_by,type:uuid,nullzero"`
+	DecidedAt   *time.Time `bun:"decided_at,nullzero"`
+	ApplyError  *string    `bun:"apply_error,nullzero"` // why an approved change could not be applied
+
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
+
+// ChangeRequestComment is a remark of a member on a change request
+type ChangeRequestComment struct {
+	bun.BaseModel `bun:"table:change_request_comments"`
+
+	ID              uuid.UUID `bun:"comment_id,pk,type:uuid,default:gen_random_uuid()"`
+	ChangeRequestID uuid.UUID `bun:"cr_id,type:uuid,notnull"`
+	UserID          uuid.UUID `bun:"user_id,type:uuid,notnull"`
+	Body            string    `bun:"body,notnull"`
+	CreatedAt       time.Time `bun:"created_at,default:current_timestamp"`
+}
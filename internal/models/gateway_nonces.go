@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// GatewayNonce is the nonce of a signed gateway request. It is kept in the
+// database so every replica rejects a replay while its timestamp is valid.
+type GatewayNonce struct {
+	bun.BaseModel `bun:"table:gateway_nonces,alias:gateway_nonce"`
+
+	Nonce     string    `bun:"nonce,pk"` // "<keyId>:<nonce>"
+	ExpiresAt time.Time `bun:"expires_at,notnull"`
+}
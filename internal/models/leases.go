@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Lease struct {
+	bun.BaseModel `bun:"table:leases"`
+
+	ID        string    `bun:"lease_id,pk"` // <projectId>/<secretName>/<random>
+	ProjectID uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	SecretID  uuid.UUID `bun:"secret_id,type:uuid,notnull"`
+	UserID    uuid.UUID `bun:"user_id,type:uuid,nullzero"` // holder of the lease
+
+	IssuedAt     time.Time  `bun:"issued_at,default:current_timestamp"`
+	ExpiresAt    time.Time  `bun:"expires_at,notnull"`
+	MaxExpiresAt time.Time  `bun:"max_expires_at,notnull"` // renewals never go past this
+	RevokedAt    *time.Time `bun:"revoked_at,nullzero"`
+	RevokeReason *string    `bun:"revoke_reason,nullzero"` // "revoked" or "expired"
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ProjectMember gives a user a role on a project. The user owning a project
+// (Project.UserID while OwnerType is user) is always an owner and needs no row here.
+type ProjectMember struct {
+	bun.BaseModel `bun:"table:project_members"`
+
+	ProjectID uuid.UUID `bun:"project_id,pk,type:uuid"`
+	UserID    uuid.UUID `bun:"user_id,pk,type:uuid"`
+	Role      string    `bun:"role,notnull"` // owner, admin, writer, reader or metadata-only
+	AddedBy   uuid.UUID `bun:"added_by,type:uuid,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
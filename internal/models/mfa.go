@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// MFAFactor is a TOTP authenticator a user enrolled with Cryptex, used to step
+// up before reading sensitive secrets
+type MFAFactor struct {
+	bun.BaseModel `bun:"table:mfa_factors,alias:mfa_factor"`
+
+	ID     uuid.UUID `bun:"factor_id,pk,type:uuid,default:gen_random_uuid()"`
+	UserID uuid.UUID `bun:"user_id,type:uuid,notnull,unique"` // one factor per user
+	Kind   string    `bun:"kind,notnull,default:'totp'"`
+	Secret string    `bun:"secret,notnull" json:"-"` // encrypted TOTP secret
+
+	ConfirmedAt  *time.Time `bun:"confirmed_at,nullzero"`   // nil until the first code is verified
+	LastUsedStep *int64     `bun:"last_used_step,nullzero"` // TOTP step of the last accepted code
+	LastUsedAt   *time.Time `bun:"last_used_at,nullzero"`
+	CreatedAt    time.Time  `bun:"created_at,default:current_timestamp"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type NotificationChannel struct {
+	bun.BaseModel `bun:"table:notification_channels"`
+
+	ID            uuid.UUID `bun:"channel_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID     uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	Name          string    `bun:"c_name,notnull"`
+	WebhookURL    string    `bun:"webhook_url,notnull"`
+	SigningSecret *string   `bun:"signing_secret,nullzero" json:"-"` // encrypted, signs every notification
+	Enabled       bool      `bun:"enabled,notnull,default:true"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+}
+
+// NotificationRecord remembers alerts that must only be sent once,
+// e.g. each expiry threshold of a secret.
+type NotificationRecord struct {
+	bun.BaseModel `bun:"table:notification_records"`
+
+	ID        uuid.UUID `bun:"record_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	SecretID  uuid.UUID `bun:"secret_id,type:uuid,nullzero"`
+	Event     string    `bun:"event,notnull"`
+	DedupKey  string    `bun:"dedup_key,notnull,unique"`
+
+	SentAt time.Time `bun:"sent_at,default:current_timestamp"`
+}
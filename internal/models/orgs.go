@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Organization can own projects. Its members and admins are not stored: the
+// gateway sends them with every request (X-User-Orgs).
+type Organization struct {
+	bun.BaseModel `bun:"table:organizations"`
+
+	ID        uuid.UUID `bun:"org_id,pk,type:uuid,default:gen_random_uuid()"`
+	Slug      string    `bun:"org_slug,notnull,unique"` // how the gateway names the org
+	Name      string    `bun:"org_name,notnull"`
+	CreatedBy uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
+
+// Team is a group of an organization that can own projects. Membership also
+// comes from the gateway (X-User-Teams, as "<orgSlug>/<teamSlug>").
+type Team struct {
+	bun.BaseModel `bun:"table:teams"`
+
+	ID        uuid.UUID `bun:"team_id,pk,type:uuid,default:gen_random_uuid()"`
+	OrgID     uuid.UUID `bun:"org_id,type:uuid,notnull,unique:org_team"`
+	Slug      string    `bun:"team_slug,notnull,unique:org_team"`
+	Name      string    `bun:"team_name,notnull"`
+	CreatedBy uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
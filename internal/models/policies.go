@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Policy grants or denies capabilities on project/secret paths to the
+// principals it is attached to
+type Policy struct {
+	bun.BaseModel `bun:"table:policies"`
+
+	ID          uuid.UUID    `bun:"policy_id,pk,type:uuid,default:gen_random_uuid()"`
+	Name        string       `bun:"policy_name,notnull,unique"`
+	Description *string      `bun:"description,nullzero"`
+	Rules       []PolicyRule `bun:"rules,type:jsonb,notnull"`
+	CreatedBy   uuid.UUID    `bun:"created_by,type:uuid,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
+
+// PolicyRule applies capabilities to every path matching a glob like
+// "<projectId>/DB_*". "*" stays within one path segment, "**" crosses them.
+type PolicyRule struct {
+	Path         string   `json:"path"`
+	Capabilities []string `json:"capabilities"` // read, create, update, delete, revoke, list or deny
+}
+
+// PolicyAttachment binds a policy to a user, a gateway group or a service account
+type PolicyAttachment struct {
+	bun.BaseModel `bun:"table:policy_attachments"`
+
+	ID            uuid.UUID `bun:"attachment_id,pk,type:uuid,default:gen_random_uuid()"`
+	PolicyID      uuid.UUID `bun:"policy_id,type:uuid,notnull,unique:policy_principal"`
+	PrincipalType string    `bun:"principal_type,notnull,unique:policy_principal"` // user, group or service_account
+	PrincipalID   string    `bun:"principal_id,notnull,unique:policy_principal"`
+	CreatedBy     uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+	CreatedAt     time.Time `bun:"created_at,default:current_timestamp"`
+}
@@ -11,10 +11,22 @@ type Project struct {
 	bun.BaseModel `bun:"table:projects"`
 
 	ID          uuid.UUID `bun:"project_id,pk,type:uuid,default:gen_random_uuid()"`
-	UserID      uuid.UUID `bun:"user_id,type:uuid,notnull"`
+	UserID      uuid.UUID `bun:"user_id,type:uuid,notnull"` // creator, and the owner while OwnerType is user
 	Name        string    `bun:"project_name,notnull"`
 	Description *string   `bun:"p_description,nullzero"`
 
+	// a project is owned by a user (UserID), a team or an organization
+	OwnerType string     `bun:"owner_type,notnull,default:'user'"`
+	OwnerID   *uuid.UUID `bun:"owner_id,type:uuid,nullzero"` // team or org ID
+	OrgID     *uuid.UUID `bun:"org_id,type:uuid,nullzero"`   // org the project belongs to, for org admins and listing
+
+	MinTTL *int64 `bun:"min_ttl_seconds,nullzero"` // bounds for the lifetime of secrets in this project
+	MaxTTL *int64 `bun:"max_ttl_seconds,nullzero"`
+
+	RequireApproval bool `bun:"require_approval,notnull,default:false"` // secret changes go through change requests
+
+	AllowedCIDRs []string `bun:"allowed_cidrs,type:jsonb,nullzero"` // where secrets may be read from; empty allows any address
+
 	CreatedAt time.Time  `bun:"created_at,default:current_timestamp"`
 	UpdatedAt time.Time  `bun:"updated_at,default:current_timestamp"`
 	DeletedAt *time.Time `bun:"deleted_at,nullzero"`
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type RotationPolicy struct {
+	bun.BaseModel `bun:"table:rotation_policies"`
+
+	ID        uuid.UUID `bun:"policy_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	SecretID  uuid.UUID `bun:"secret_id,type:uuid,notnull,unique"`
+
+	IntervalHours *int    `bun:"interval_hours,nullzero"` // either an interval or a cron expression
+	CronExpr      *string `bun:"cron_expr,nullzero"`
+
+	Rotator       string                `bun:"rotator,notnull"` // "generator" or "webhook"
+	Generate      *utils.GeneratePolicy `bun:"generate,type:jsonb,nullzero"`
+	WebhookURL    *string               `bun:"webhook_url,nullzero"`
+	WebhookSecret *string               `bun:"webhook_secret,nullzero" json:"-"` // encrypted, signs calls to the webhook
+
+	Enabled      bool       `bun:"enabled,notnull,default:true"`
+	NextRunAt    time.Time  `bun:"next_run_at,notnull"`
+	LastRunAt    *time.Time `bun:"last_run_at,nullzero"`
+	LastError    *string    `bun:"last_error,nullzero"`
+	FailureCount int        `bun:"failure_count,notnull,default:0"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
@@ -16,10 +16,23 @@ type Secret struct {
 	Value     string    `bun:"s_value,notnull"`
 	Version   int       `bun:"secret_version,notnull,default:1"`
 
-	TTL       *int       `bun:"ttl,nullzero"` // store minutes/hours as integer
+	TTL       *int64     `bun:"ttl_seconds,nullzero"` // lifetime in seconds
 	Revoked   bool       `bun:"revoked,notnull,default:false"`
 	CreatedAt time.Time  `bun:"created_at,default:current_timestamp"`
 	UpdatedAt time.Time  `bun:"updated_at,default:current_timestamp"`
-	ExpiresAt *time.Time `bun:"expires_at,nullzero"` //obtained from TTL and createdAt
+	NotBefore *time.Time `bun:"not_before,nullzero"` // secret cannot be read before this time
+	ExpiresAt *time.Time `bun:"expires_at,nullzero"` //obtained from TTL and createdAt/notBefore
 	DeletedAt *time.Time `bun:"deleted_at,nullzero"`
+
+	DeletedWithProject bool `bun:"deleted_with_project,notnull,default:false"` // restored together with its project
+
+	LeaseTTL    *int64 `bun:"lease_ttl_seconds,nullzero"` // set when every read issues a lease
+	LeaseMaxTTL *int64 `bun:"lease_max_ttl_seconds,nullzero"`
+
+	Sensitive bool `bun:"sensitive,notnull,default:false"` // reading needs step-up authentication
+
+	AllowedCIDRs []string `bun:"allowed_cidrs,type:jsonb,nullzero"` // narrows the project allowlist for this secret; empty allows any address
+
+	MaxReads       *int `bun:"max_reads,nullzero"`       // secret is destroyed after this many reads
+	ReadsRemaining *int `bun:"reads_remaining,nullzero"` // only changed through ConsumeRead
 }
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// ServiceAccount is a machine identity bound to one project, optionally
+// limited to the secrets whose name starts with PathPrefix
+type ServiceAccount struct {
+	bun.BaseModel `bun:"table:service_accounts"`
+
+	ID          uuid.UUID `bun:"sa_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID   uuid.UUID `bun:"project_id,type:uuid,notnull,unique:project_sa"`
+	Name        string    `bun:"sa_name,notnull,unique:project_sa"`
+	Description *string   `bun:"description,nullzero"`
+	PathPrefix  string    `bun:"path_prefix,notnull,default:''"`
+	CreatedBy   uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+
+	AllowedCIDRs []string `bun:"allowed_cidrs,type:jsonb,nullzero"` // where its tokens may read secrets from; empty allows any address
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
+
+// ServiceToken is an API token of a service account. Only its hash is stored.
+type ServiceToken struct {
+	bun.BaseModel `bun:"table:service_tokens"`
+
+	ID               uuid.UUID `bun:"token_id,pk,type:uuid,default:gen_random_uuid()"`
+	ServiceAccountID uuid.UUID `bun:"sa_id,type:uuid,notnull"`
+	ProjectID        uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	TokenHash        string    `bun:"token_hash,notnull,unique" json:"-"`
+	TokenPrefix      string    `bun:"token_prefix,notnull"` // first characters, to recognise a token
+	Label            string    `bun:"label,notnull,default:''"`
+	Scopes           []string  `bun:"scopes,type:jsonb,notnull"`         // read, list, create, update, delete, revoke
+	AllowedCIDRs     []string  `bun:"allowed_cidrs,type:jsonb,nullzero"` // empty allows any address
+	CreatedBy        uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+
+	ExpiresAt  time.Time  `bun:"expires_at,notnull"`
+	LastUsedAt *time.Time `bun:"last_used_at,nullzero"`
+	LastUsedIP *string    `bun:"last_used_ip,nullzero"`
+	RevokedAt  *time.Time `bun:"revoked_at,nullzero"`
+	CreatedAt  time.Time  `bun:"created_at,default:current_timestamp"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Share is a link that lets a person outside the project view a value a few
+// times. The value is encrypted under a key derived from the link fragment,
+// which the server never stores.
+type Share struct {
+	bun.BaseModel `bun:"table:shares"`
+
+	ID        uuid.UUID  `bun:"share_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID uuid.UUID  `bun:"project_id,type:uuid,notnull"`
+	SecretID  *uuid.UUID `bun:"secret_id,type:uuid,nullzero"` // set when an existing secret was snapshotted
+	CreatedBy uuid.UUID  `bun:"created_by,type:uuid,notnull"`
+	Label     string     `bun:"label"`
+
+	Ciphertext         string `bun:"ciphertext,notnull" json:"-"` // cleared once the share is used up or revoked
+	Salt               string `bun:"salt,notnull" json:"-"`
+	PassphraseRequired bool   `bun:"passphrase_required,notnull,default:false"`
+
+	MaxViews  int        `bun:"max_views,notnull,default:1"`
+	Views     int        `bun:"views,notnull,default:0"`
+	ExpiresAt time.Time  `bun:"expires_at,notnull"`
+	RevokedAt *time.Time `bun:"revoked_at,nullzero"`
+	CreatedAt time.Time  `bun:"created_at,default:current_timestamp"`
+}
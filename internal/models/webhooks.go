@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type WebhookSubscription struct {
+	bun.BaseModel `bun:"table:webhook_subscriptions"`
+
+	ID            uuid.UUID `bun:"subscription_id,pk,type:uuid,default:gen_random_uuid()"`
+	ProjectID     uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	URL           string    `bun:"webhook_url,notnull"`
+	Events        []string  `bun:"events,type:jsonb"`               // event filter, e.g. secret.created or secret.*
+	SigningSecret string    `bun:"signing_secret,notnull" json:"-"` // encrypted HMAC key
+	Enabled       bool      `bun:"enabled,notnull,default:true"`
+	CreatedBy     uuid.UUID `bun:"created_by,type:uuid,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+	UpdatedAt time.Time `bun:"updated_at,default:current_timestamp"`
+}
+
+// WebhookDelivery is one queued event for one subscription.
+// Deliveries that exhaust their retries stay in the table as "dead".
+type WebhookDelivery struct {
+	bun.BaseModel `bun:"table:webhook_deliveries"`
+
+	ID             uuid.UUID `bun:"delivery_id,pk,type:uuid,default:gen_random_uuid()"`
+	SubscriptionID uuid.UUID `bun:"subscription_id,type:uuid,notnull"`
+	ProjectID      uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	Event          string    `bun:"event,notnull"`
+	Payload        string    `bun:"payload,type:jsonb,notnull"`
+
+	Status         string     `bun:"status,notnull,default:'pending'"` // pending, delivered, dead
+	Attempts       int        `bun:"attempts,notnull,default:0"`
+	NextAttemptAt  time.Time  `bun:"next_attempt_at,notnull"`
+	LastError      *string    `bun:"last_error,nullzero"`
+	LastStatusCode *int       `bun:"last_status_code,nullzero"`
+	DeliveredAt    *time.Time `bun:"delivered_at,nullzero"`
+
+	CreatedAt time.Time `bun:"created_at,default:current_timestamp"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// WrappedToken is a single-use token that delivers one read of a secret
+type WrappedToken struct {
+	bun.BaseModel `bun:"table:wrapped_tokens"`
+
+	ID        uuid.UUID `bun:"token_id,pk,type:uuid,default:gen_random_uuid()"`
+	TokenHash string    `bun:"token_hash,notnull,unique"` // sha256 of the token, the token itself is never stored
+	ProjectID uuid.UUID `bun:"project_id,type:uuid,notnull"`
+	SecretID  uuid.UUID `bun:"secret_id,type:uuid,notnull"`
+	CreatedBy uuid.UUID `bun:"created_by,type:uuid,notnull"`
+
+	// snapshot of the read, encrypted like secrets; cleared once unwrapped
+	Value         string  `bun:"s_value,notnull"`
+	SecretName    string  `bun:"s_name,notnull"`
+	SecretVersion int     `bun:"secret_version,notnull"`
+	LeaseID       *string `bun:"lease_id,nullzero"` // lease issued by the wrapped read, if any
+
+	ExpiresAt   time.Time  `bun:"expires_at,notnull"`
+	UnwrappedAt *time.Time `bun:"unwrapped_at,nullzero"`
+	CreatedAt   time.Time  `bun:"created_at,default:current_timestamp"`
+}
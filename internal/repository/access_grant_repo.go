@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type AccessGrantRepository struct{}
+
+func NewAccessGrantRepository() *AccessGrantRepository {
+	return &AccessGrantRepository{}
+}
+
+func (gr *AccessGrantRepository) CreateGrant(ctx context.Context, grant *models.AccessGrant) error {
+	_, err := database.DB.NewInsert().
+		Model(grant).
+		Exec(ctx)
+	return err
+}
+
+func (gr *AccessGrantRepository) GetGrantByID(ctx context.Context, grantID string) (*models.AccessGrant, error) {
+	var grant models.AccessGrant
+	err := database.DB.NewSelect().
+		Model(&grant).
+		Where("grant_id = ?", grantID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &grant, nil
+}
+
+// GetGrantsByProject lists the grants of a project, newest first. An empty
+// userID lists everyone's, an empty status every status.
+func (gr *AccessGrantRepository) GetGrantsByProject(ctx context.Context, projectID string, userID string, status string) ([]models.AccessGrant, error) {
+	var grants []models.AccessGrant
+	q := database.DB.NewSelect().
+		Model(&grants).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC")
+	if userID != "" {
+		q = q.Where("user_id = ?", userID)
+	}
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	err := q.Scan(ctx)
+	return grants, err
+}
+
+// GetActiveGrants returns the unexpired active grants of a user on a project
+func (gr *AccessGrantRepository) GetActiveGrants(ctx context.Context, userID string, projectID string) ([]models.AccessGrant, error) {
+	var grants []models.AccessGrant
+	err := database.DB.NewSelect().
+		Model(&grants).
+		Where("user_id = ?", userID).
+		Where("project_id = ?", projectID).
+		Where("status = ?", "active").
+		Where("expires_at > ?", time.Now()).
+		Scan(ctx)
+	return grants, err
+}
+
+// Transition moves a grant from one status to the one set on it together
+// with its decision, expiry and revocation. It reports false when the grant
+// was no longer in from.
+func (gr *AccessGrantRepository) Transition(ctx context.Context, grant *models.AccessGrant, from string) (bool, error) {
+	res, err := database.DB.NewUpdate().
+		Model(grant).
+		Column("status", "decided_by", "decided_at", "expires_at", "revoked_by", "revoked_at").
+		Where("grant_id = ?", grant.ID).
+		Where("status = ?", from).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ExpireGrants marks the active grants that ran out by now as expired and
+// returns them
+func (gr *AccessGrantRepository) ExpireGrants(ctx context.Context, now time.Time) ([]models.AccessGrant, error) {
+	var grants []models.AccessGrant
+	err := database.DB.NewUpdate().
+		Model((*models.AccessGrant)(nil)).
+		Set("status = ?", "expired").
+		Where("status = ?", "active").
+		Where("expires_at <= ?", now).
+		Returning("*").
+		Scan(ctx, &grants)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return grants, nil
+}
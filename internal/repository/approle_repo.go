@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type AppRoleRepository struct{}
+
+func NewAppRoleRepository() *AppRoleRepository {
+	return &AppRoleRepository{}
+}
+
+func (ar *AppRoleRepository) CreateRole(ctx context.Context, role *models.AppRole) error {
+	_, err := database.DB.NewInsert().
+		Model(role).
+		Exec(ctx)
+	return err
+}
+
+func (ar *AppRoleRepository) GetRoleByID(ctx context.Context, roleID string) (*models.AppRole, error) {
+	var role models.AppRole
+	err := database.DB.NewSelect().
+		Model(&role).
+		Where("role_id = ?", roleID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (ar *AppRoleRepository) GetRoles(ctx context.Context) ([]models.AppRole, error) {
+	var roles []models.AppRole
+	err := database.DB.NewSelect().
+		Model(&roles).
+		Order("role_name ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// DeleteRole deletes a role together with its secret IDs
+func (ar *AppRoleRepository) DeleteRole(ctx context.Context, roleID string) error {
+	return database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*models.AppRoleSecretID)(nil)).
+			Where("role_id = ?", roleID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.AppRole)(nil)).
+			Where("role_id = ?", roleID).
+			Exec(ctx)
+		return err
+	})
+}
+
+func (ar *AppRoleRepository) CreateSecretID(ctx context.Context, secretID *models.AppRoleSecretID) error {
+	_, err := database.DB.NewInsert().
+		Model(secretID).
+		Exec(ctx)
+	return err
+}
+
+func (ar *AppRoleRepository) GetSecretIDsByRole(ctx context.Context, roleID string) ([]models.AppRoleSecretID, error) {
+	var secretIDs []models.AppRoleSecretID
+	err := database.DB.NewSelect().
+		Model(&secretIDs).
+		Where("role_id = ?", roleID).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return secretIDs, nil
+}
+
+func (ar *AppRoleRepository) DeleteSecretID(ctx context.Context, roleID string, accessor string) (bool, error) {
+	res, err := database.DB.NewDelete().
+		Model((*models.AppRoleSecretID)(nil)).
+		Where("accessor = ?", accessor).
+		Where("role_id = ?", roleID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ConsumeSecretID spends one use of a live secret ID of the role and returns
+// it, or nil when it is unknown, expired or used up. A secret ID whose last
+// use this was is deleted.
+func (ar *AppRoleRepository) ConsumeSecretID(ctx context.Context, roleID string, hash string) (*models.AppRoleSecretID, error) {
+	var secretIDs []models.AppRoleSecretID
+	now := time.Now()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewUpdate().
+			Model((*models.AppRoleSecretID)(nil)).
+			Set("uses_remaining = uses_remaining - 1").
+			Set("last_used_at = ?", now).
+			Where("role_id = ?", roleID).
+			Where("secret_hash = ?", hash).
+			Where("uses_remaining IS NULL OR uses_remaining > 0").
+			Where("expires_at IS NULL OR expires_at > ?", now).
+			Returning("*").
+			Scan(ctx, &secretIDs)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if len(secretIDs) == 0 || secretIDs[0].UsesRemaining == nil || *secretIDs[0].UsesRemaining > 0 {
+			return nil
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.AppRoleSecretID)(nil)).
+			Where("accessor = ?", secretIDs[0].Accessor).
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(secretIDs) == 0 {
+		return nil, nil
+	}
+	return &secretIDs[0], nil
+}
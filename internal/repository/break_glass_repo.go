@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type BreakGlassRepository struct{}
+
+func NewBreakGlassRepository() *BreakGlassRepository {
+	return &BreakGlassRepository{}
+}
+
+func (br *BreakGlassRepository) CreateSession(ctx context.Context, session *models.BreakGlassSession) error {
+	_, err := database.DB.NewInsert().
+		Model(session).
+		Exec(ctx)
+	return err
+}
+
+func (br *BreakGlassRepository) GetSessionByID(ctx context.Context, sessionID string) (*models.BreakGlassSession, error) {
+	var session models.BreakGlassSession
+	err := database.DB.NewSelect().
+		Model(&session).
+		Where("session_id = ?", sessionID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// GetActiveSession returns the open, unexpired session of a user on a
+// project, or nil
+func (br *BreakGlassRepository) GetActiveSession(ctx context.Context, userID string, projectID string) (*models.BreakGlassSession, error) {
+	var session models.BreakGlassSession
+	err := database.DB.NewSelect().
+		Model(&session).
+		Where("user_id = ?", userID).
+		Where("project_id = ?", projectID).
+		Where("ended_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Order("created_at DESC").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (br *BreakGlassRepository) GetSessionsByProject(ctx context.Context, projectID string) ([]models.BreakGlassSession, error) {
+	var sessions []models.BreakGlassSession
+	err := database.DB.NewSelect().
+		Model(&sessions).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Scan(ctx)
+	return sessions, err
+}
+
+// EndSession closes a session that is still open. It reports false when it
+// already ended or expired.
+func (br *BreakGlassRepository) EndSession(ctx context.Context, session *models.BreakGlassSession) (bool, error) {
+	res, err := database.DB.NewUpdate().
+		Model(session).
+		Column("ended_at", "ended_by").
+		Where("session_id = ?", session.ID).
+		Where("ended_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
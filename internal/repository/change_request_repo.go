@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type ChangeRequestRepository struct{}
+
+func NewChangeRequestRepository() *ChangeRequestRepository {
+	return &ChangeRequestRepository{}
+}
+
+func (cr *ChangeRequestRepository) CreateChangeRequest(ctx context.Context, request *models.ChangeRequest) error {
+	_, err := database.DB.NewInsert().
+		Model(request).
+		Exec(ctx)
+	return err
+}
+
+func (cr *ChangeRequestRepository) GetChangeRequestByID(ctx context.Context, requestID string) (*models.ChangeRequest, error) {
+	var request models.ChangeRequest
+	err := database.DB.NewSelect().
+		Model(&request).
+		Where("cr_id = ?", requestID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &request, nil
+}
+
+// GetChangeRequestsByProject lists the requests of a project, newest first.
+// An empty status lists all of them.
+func (cr *ChangeRequestRepository) GetChangeRequestsByProject(ctx context.Context, projectID string, status string) ([]models.ChangeRequest, error) {
+	var requests []models.ChangeRequest
+	q := database.DB.NewSelect().
+		Model(&requests).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	err := q.Scan(ctx)
+	return requests, err
+}
+
+// Transition moves a request from one status to the one set on it, together
+// with its decision and outcome. It reports false when the request was no
+// longer in from, so two approvers cannot both act on it. An expired request
+// cannot leave pending this way.
+func (cr *ChangeRequestRepository) Transition(ctx context.Context, request *models.ChangeRequest, from string) (bool, error) {
+	request.UpdatedAt = time.Now()
+	q := database.DB.NewUpdate().
+		Model(request).
+		Column("status", "secret_id", "decided_by", "decided_at", "apply_error", "updated_at").
+		Where("cr_id = ?", request.ID).
+		Where("status = ?", from)
+	if from == "pending" {
+		q = q.Where("expires_at > ?", time.Now())
+	}
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ExpirePending marks the pending requests of a project whose time ran out as
+// expired and returns them
+func (cr *ChangeRequestRepository) ExpirePending(ctx context.Context, projectID string) ([]models.ChangeRequest, error) {
+	var expired []models.ChangeRequest
+	now := time.Now()
+	err := database.DB.NewUpdate().
+		Model((*models.ChangeRequest)(nil)).
+		Set("status = ?", "expired").
+		Set("updated_at = ?", now).
+		Where("project_id = ?", projectID).
+		Where("status = ?", "pending").
+		Where("expires_at <= ?", now).
+		Returning("*").
+		Scan(ctx, &expired)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return expired, nil
+}
+
+func (cr *ChangeRequestRepository) CreateComment(ctx context.Context, comment *models.ChangeRequestComment) error {
+	_, err := database.DB.NewInsert().
+		Model(comment).
+		Exec(ctx)
+	return err
+}
+
+func (cr *ChangeRequestRepository) GetComments(ctx context.Context, requestID string) ([]models.ChangeRequestComment, error) {
+	var comments []models.ChangeRequestComment
+	err := database.DB.NewSelect().
+		Model(&comments).
+		Where("cr_id = ?", requestID).
+		Order("created_at ASC").
+		Scan(ctx)
+	return comments, err
+}
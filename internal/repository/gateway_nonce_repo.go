@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type GatewayNonceRepository struct{}
+
+func NewGatewayNonceRepository() *GatewayNonceRepository {
+	return &GatewayNonceRepository{}
+}
+
+// RecordNonce stores a nonce until expiresAt. It reports false when the nonce
+// is already stored and has not expired, i.e. the request is a replay. An
+// expired entry is taken over.
+func (gr *GatewayNonceRepository) RecordNonce(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	res, err := database.DB.NewInsert().
+		Model(&models.GatewayNonce{Nonce: nonce, ExpiresAt: expiresAt}).
+		On("CONFLICT (nonce) DO UPDATE").
+		Set("expires_at = EXCLUDED.expires_at").
+		Where("gateway_nonce.expires_at < ?", time.Now()).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// DeleteExpiredNonces forgets the nonces whose requests could no longer be replayed
+func (gr *GatewayNonceRepository) DeleteExpiredNonces(ctx context.Context) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.GatewayNonce)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Exec(ctx)
+	return err
+}
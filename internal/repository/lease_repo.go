@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"unicode/utf8"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type LeaseRepository struct{}
+
+func NewLeaseRepository() *LeaseRepository {
+	return &LeaseRepository{}
+}
+
+func (lr *LeaseRepository) CreateLease(ctx context.Context, lease *models.Lease) error {
+	_, err := database.DB.NewInsert().
+		Model(lease).
+		Exec(ctx)
+	return err
+}
+
+func (lr *LeaseRepository) GetLeaseByID(ctx context.Context, leaseID string) (*models.Lease, error) {
+	var lease models.Lease
+	err := database.DB.NewSelect().
+		Model(&lease).
+		Where("lease_id = ?", leaseID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lease, nil
+}
+
+// RenewLease moves the expiry of a live lease; it reports false when the lease
+// was revoked or expired in the meantime
+func (lr *LeaseRepository) RenewLease(ctx context.Context, leaseID string, expiresAt time.Time) (bool, error) {
+	res, err := database.DB.NewUpdate().
+		Model((*models.Lease)(nil)).
+		Set("expires_at = ?", expiresAt).
+		Where("lease_id = ?", leaseID).
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", time.Now()).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// RevokeLease revokes a single live lease and returns it, or nil if it was not live
+func (lr *LeaseRepository) RevokeLease(ctx context.Context, leaseID string, reason string) (*models.Lease, error) {
+	var leases []models.Lease
+	err := database.DB.NewUpdate().
+		Model((*models.Lease)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Set("revoke_reason = ?", reason).
+		Where("lease_id = ?", leaseID).
+		Where("revoked_at IS NULL").
+		Returning("*").
+		Scan(ctx, &leases)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if len(leases) == 0 {
+		return nil, nil
+	}
+	return &leases[0], nil
+}
+
+// GetLeasesByPrefix lists the leases whose ID starts with prefix, newest first
+func (lr *LeaseRepository) GetLeasesByPrefix(ctx context.Context, prefix string, includeRevoked bool) ([]models.Lease, error) {
+	var leases []models.Lease
+
+	// left() instead of LIKE, so "_" and "%" in secret names match literally
+	q := database.DB.NewSelect().
+		Model(&leases).
+		Where("left(lease_id, ?) = ?", utf8.RuneCountInString(prefix), prefix)
+	if !includeRevoked {
+		q = q.Where("revoked_at IS NULL")
+	}
+
+	err := q.Order("issued_at DESC").Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// RevokeLeasesByPrefix revokes every live lease whose ID starts with prefix
+func (lr *LeaseRepository) RevokeLeasesByPrefix(ctx context.Context, prefix string, reason string) ([]models.Lease, error) {
+	var leases []models.Lease
+	err := database.DB.NewUpdate().
+		Model((*models.Lease)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Set("revoke_reason = ?", reason).
+		Where("left(lease_id, ?) = ?", utf8.RuneCountInString(prefix), prefix).
+		Where("revoked_at IS NULL").
+		Returning("*").
+		Scan(ctx, &leases)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// RevokeLeasesBySecret revokes every live lease issued for a secret
+func (lr *LeaseRepository) RevokeLeasesBySecret(ctx context.Context, secretID string, reason string) ([]models.Lease, error) {
+	var leases []models.Lease
+	err := database.DB.NewUpdate().
+		Model((*models.Lease)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Set("revoke_reason = ?", reason).
+		Where("secret_id = ?", secretID).
+		Where("revoked_at IS NULL").
+		Returning("*").
+		Scan(ctx, &leases)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return leases, nil
+}
+
+// ExpireLeases marks every lease that ran past its expiry and returns them
+func (lr *LeaseRepository) ExpireLeases(ctx context.Context, now time.Time) ([]models.Lease, error) {
+	var leases []models.Lease
+	err := database.DB.NewUpdate().
+		Model((*models.Lease)(nil)).
+		Set("revoked_at = ?", now).
+		Set("revoke_reason = 'expired'").
+		Where("expires_at <= ?", now).
+		Where("revoked_at IS NULL").
+		Returning("*").
+		Scan(ctx, &leases)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return leases, nil
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type MemberRepository struct{}
+
+func NewMemberRepository() *MemberRepository {
+	return &MemberRepository{}
+}
+
+// AddMember inserts a membership; it reports false when the user already is a member
+func (mr *MemberRepository) AddMember(ctx context.Context, member *models.ProjectMember) (bool, error) {
+	res, err := database.DB.NewInsert().
+		Model(member).
+		On("CONFLICT (project_id, user_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (mr *MemberRepository) GetMember(ctx context.Context, projectID string, userID string) (*models.ProjectMember, error) {
+	var member models.ProjectMember
+	err := database.DB.NewSelect().
+		Model(&member).
+		Where("project_id = ?", projectID).
+		Where("user_id = ?", userID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (mr *MemberRepository) GetMembersByProject(ctx context.Context, projectID string) ([]models.ProjectMember, error) {
+	var members []models.ProjectMember
+	err := database.DB.NewSelect().
+		Model(&members).
+		Where("project_id = ?", projectID).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (mr *MemberRepository) UpdateMemberRole(ctx context.Context, projectID string, userID string, role string) error {
+	_, err := database.DB.NewUpdate().
+		Model((*models.ProjectMember)(nil)).
+		Set("role = ?", role).
+		Set("updated_at = ?", time.Now()).
+		Where("project_id = ?", projectID).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
+
+func (mr *MemberRepository) RemoveMember(ctx context.Context, projectID string, userID string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.ProjectMember)(nil)).
+		Where("project_id = ?", projectID).
+		Where("user_id = ?", userID).
+		Exec(ctx)
+	return err
+}
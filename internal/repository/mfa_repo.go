@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type MFARepository struct{}
+
+func NewMFARepository() *MFARepository {
+	return &MFARepository{}
+}
+
+func (mr *MFARepository) GetFactorByUser(ctx context.Context, userID string) (*models.MFAFactor, error) {
+	var factor models.MFAFactor
+	err := database.DB.NewSelect().
+		Model(&factor).
+		Where("user_id = ?", userID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &factor, nil
+}
+
+// ReplaceUnconfirmedFactor stores a new factor for the user, replacing one
+// that was never confirmed. It reports false when a confirmed factor exists.
+func (mr *MFARepository) ReplaceUnconfirmedFactor(ctx context.Context, factor *models.MFAFactor) (bool, error) {
+	res, err := database.DB.NewInsert().
+		Model(factor).
+		On("CONFLICT (user_id) DO UPDATE").
+		Set("factor_id = EXCLUDED.factor_id").
+		Set("secret = EXCLUDED.secret").
+		Set("created_at = EXCLUDED.created_at").
+		Where("mfa_factor.confirmed_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// UseStep records that the code of a TOTP step was accepted. It reports false
+// when that step or a later one was already used, so a code works only once.
+// confirm also marks an unconfirmed factor as confirmed.
+func (mr *MFARepository) UseStep(ctx context.Context, factorID string, step int64, confirm bool) (bool, error) {
+	q := database.DB.NewUpdate().
+		Model((*models.MFAFactor)(nil)).
+		Set("last_used_step = ?", step).
+		Set("last_used_at = ?", time.Now()).
+		Where("factor_id = ?", factorID).
+		Where("last_used_step IS NULL OR last_used_step < ?", step)
+	if confirm {
+		q = q.Set("confirmed_at = ?", time.Now())
+	}
+	res, err := q.Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (mr *MFARepository) DeleteFactor(ctx context.Context, factorID string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.MFAFactor)(nil)).
+		Where("factor_id = ?", factorID).
+		Exec(ctx)
+	return err
+}
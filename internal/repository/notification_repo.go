@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type NotificationRepository struct{}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{}
+}
+
+func (nr *NotificationRepository) CreateChannel(ctx context.Context, channel *models.NotificationChannel) error {
+	_, err := database.DB.NewInsert().
+		Model(channel).
+		Exec(ctx)
+	return err
+}
+
+func (nr *NotificationRepository) GetChannelByID(ctx context.Context, channelID string) (*models.NotificationChannel, error) {
+	var channel models.NotificationChannel
+	err := database.DB.NewSelect().
+		Model(&channel).
+		Where("channel_id = ?", channelID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &channel, nil
+}
+
+func (nr *NotificationRepository) GetChannelsByProject(ctx context.Context, projectID string) ([]models.NotificationChannel, error) {
+	var channels []models.NotificationChannel
+	err := database.DB.NewSelect().
+		Model(&channels).
+		Where("project_id = ?", projectID).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+func (nr *NotificationRepository) DeleteChannel(ctx context.Context, channelID string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.NotificationChannel)(nil)).
+		Where("channel_id = ?", channelID).
+		Exec(ctx)
+	return err
+}
+
+// RecordOnce stores the record unless its dedup key was already used.
+// It reports whether the record is new, i.e. whether the alert should be sent.
+func (nr *NotificationRepository) RecordOnce(ctx context.Context, record *models.NotificationRecord) (bool, error) {
+	res, err := database.DB.NewInsert().
+		Model(record).
+		On("CONFLICT (dedup_key) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// ReleaseRecord forgets a dedup key, so the alert can be sent again
+func (nr *NotificationRepository) ReleaseRecord(ctx context.Context, dedupKey string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.NotificationRecord)(nil)).
+		Where("dedup_key = ?", dedupKey).
+		Exec(ctx)
+	return err
+}
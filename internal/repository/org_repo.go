@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type OrgRepository struct{}
+
+func NewOrgRepository() *OrgRepository {
+	return &OrgRepository{}
+}
+
+func (or *OrgRepository) CreateOrg(ctx context.Context, org *models.Organization) error {
+	_, err := database.DB.NewInsert().
+		Model(org).
+		Exec(ctx)
+	return err
+}
+
+func (or *OrgRepository) GetOrgByID(ctx context.Context, orgID string) (*models.Organization, error) {
+	var org models.Organization
+	err := database.DB.NewSelect().
+		Model(&org).
+		Where("org_id = ?", orgID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+func (or *OrgRepository) GetOrgsBySlugs(ctx context.Context, slugs []string) ([]models.Organization, error) {
+	var orgs []models.Organization
+	if len(slugs) == 0 {
+		return orgs, nil
+	}
+
+	err := database.DB.NewSelect().
+		Model(&orgs).
+		Where("org_slug IN (?)", bun.In(slugs)).
+		Order("org_name ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+func (or *OrgRepository) CreateTeam(ctx context.Context, team *models.Team) error {
+	_, err := database.DB.NewInsert().
+		Model(team).
+		Exec(ctx)
+	return err
+}
+
+func (or *OrgRepository) GetTeamByID(ctx context.Context, teamID string) (*models.Team, error) {
+	var team models.Team
+	err := database.DB.NewSelect().
+		Model(&team).
+		Where("team_id = ?", teamID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (or *OrgRepository) GetTeamsByOrg(ctx context.Context, orgID string) ([]models.Team, error) {
+	var teams []models.Team
+	err := database.DB.NewSelect().
+		Model(&teams).
+		Where("org_id = ?", orgID).
+		Order("team_name ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+// GetTeamsByPaths resolves "<orgSlug>/<teamSlug>" paths, as sent by the gateway, to teams
+func (or *OrgRepository) GetTeamsByPaths(ctx context.Context, paths []string) ([]models.Team, error) {
+	var teams []models.Team
+	if len(paths) == 0 {
+		return teams, nil
+	}
+
+	err := database.DB.NewSelect().
+		Model(&teams).
+		Join("JOIN organizations AS o ON o.org_id = team.org_id").
+		Where("o.org_slug || '/' || team.team_slug IN (?)", bun.In(paths)).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return teams, nil
+}
+
+func (or *OrgRepository) DeleteTeam(ctx context.Context, teamID string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.Team)(nil)).
+		Where("team_id = ?", teamID).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/uptrace/bun"
+)
+
+type PolicyRepository struct{}
+
+func NewPolicyRepository() *PolicyRepository {
+	return &PolicyRepository{}
+}
+
+// Principal identifies who a policy is attached to
+type Principal struct {
+	Type string
+	ID   string
+}
+
+func (pr *PolicyRepository) CreatePolicy(ctx context.Context, policy *models.Policy) error {
+	_, err := database.DB.NewInsert().
+		Model(policy).
+		Exec(ctx)
+	return err
+}
+
+func (pr *PolicyRepository) GetPolicyByID(ctx context.Context, policyID string) (*models.Policy, error) {
+	var policy models.Policy
+	err := database.DB.NewSelect().
+		Model(&policy).
+		Where("policy_id = ?", policyID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (pr *PolicyRepository) GetPolicies(ctx context.Context) ([]models.Policy, error) {
+	var policies []models.Policy
+	err := database.DB.NewSelect().
+		Model(&policies).
+		Order("policy_name ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+func (pr *PolicyRepository) UpdatePolicy(ctx context.Context, policy *models.Policy) error {
+	_, err := database.DB.NewUpdate().
+		Model(policy).
+		Column("policy_name", "description", "rules", "updated_at").
+		Where("policy_id = ?", policy.ID).
+		Exec(ctx)
+	return err
+}
+
+// DeletePolicy deletes a policy together with its attachments
+func (pr *PolicyRepository) DeletePolicy(ctx context.Context, policyID string) error {
+	return database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*models.PolicyAttachment)(nil)).
+			Where("policy_id = ?", policyID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.Policy)(nil)).
+			Where("policy_id = ?", policyID).
+			Exec(ctx)
+		return err
+	})
+}
+
+// CreateAttachment attaches a policy; it reports false when it was already attached to the principal
+func (pr *PolicyRepository) CreateAttachment(ctx context.Context, attachment *models.PolicyAttachment) (bool, error) {
+	res, err := database.DB.NewInsert().
+		Model(attachment).
+		On("CONFLICT (policy_id, principal_type, principal_id) DO NOTHING").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (pr *PolicyRepository) GetAttachmentsByPolicy(ctx context.Context, policyID string) ([]models.PolicyAttachment, error) {
+	var attachments []models.PolicyAttachment
+	err := database.DB.NewSelect().
+		Model(&attachments).
+		Where("policy_id = ?", policyID).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (pr *PolicyRepository) DeleteAttachment(ctx context.Context, policyID string, attachmentID string) (bool, error) {
+	res, err := database.DB.NewDelete().
+		Model((*models.PolicyAttachment)(nil)).
+		Where("attachment_id = ?", attachmentID).
+		Where("policy_id = ?", policyID).
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// GetAttachmentsForPrincipals returns every attachment of any of the principals
+func (pr *PolicyRepository) GetAttachmentsForPrincipals(ctx context.Context, principals []Principal) ([]models.PolicyAttachment, error) {
+	var attachments []models.PolicyAttachment
+	if len(principals) == 0 {
+		return attachments, nil
+	}
+
+	err := database.DB.NewSelect().
+		Model(&attachments).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			for _, p := range principals {
+				q = q.WhereOr("principal_type = ? AND principal_id = ?", p.Type, p.ID)
+			}
+			return q
+		}).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return attachments, nil
+}
+
+func (pr *PolicyRepository) GetPoliciesByIDs(ctx context.Context, policyIDs []string) ([]models.Policy, error) {
+	var policies []models.Policy
+	if len(policyIDs) == 0 {
+		return policies, nil
+	}
+
+	err := database.DB.NewSelect().
+		Model(&policies).
+		Where("policy_id IN (?)", bun.In(policyIDs)).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
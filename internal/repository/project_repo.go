@@ -7,6 +7,7 @@ import (
 
 	"github.com/akansha204/cryptex-secretservice/internal/database"
 	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/uptrace/bun"
 )
 
 type ProjectRepository struct{}
@@ -38,12 +39,38 @@ func (pr *ProjectRepository) GetProjectByID(ctx context.Context, projectID strin
 	}
 	return &project, nil
 }
-func (pr *ProjectRepository) GetProjectsByUserID(ctx context.Context, userID string) ([]models.Project, error) {
+
+// ProjectOwnership is everything that can tie a user to a project besides
+// membership rows: the teams they are in and the orgs they administer
+type ProjectOwnership struct {
+	UserID      string
+	TeamIDs     []string
+	AdminOrgIDs []string
+}
+
+// ownedBy matches the projects owned through o, plus the ones where the user
+// has a member row matching memberFilter
+func ownedBy(o ProjectOwnership, memberFilter string) func(*bun.SelectQuery) *bun.SelectQuery {
+	return func(q *bun.SelectQuery) *bun.SelectQuery {
+		q = q.
+			Where("owner_type = 'user' AND user_id = ?", o.UserID).
+			WhereOr("project_id IN (SELECT project_id FROM project_members WHERE user_id = ?"+memberFilter+")", o.UserID)
+		if len(o.TeamIDs) > 0 {
+			q = q.WhereOr("owner_type = 'team' AND owner_id IN (?)", bun.In(o.TeamIDs))
+		}
+		if len(o.AdminOrgIDs) > 0 {
+			q = q.WhereOr("org_id IN (?)", bun.In(o.AdminOrgIDs))
+		}
+		return q
+	}
+}
+
+func (pr *ProjectRepository) GetProjectsByUserID(ctx context.Context, ownership ProjectOwnership) ([]models.Project, error) {
 	var projects []models.Project
 
 	err := database.DB.NewSelect().
 		Model(&projects).
-		Where("user_id = ?", userID).
+		WhereGroup(" AND ", ownedBy(ownership, "")).
 		Where("deleted_at IS NULL").
 		Order("created_at DESC").
 		Scan(ctx)
@@ -62,11 +89,244 @@ func (pr *ProjectRepository) UpdateProject(ctx context.Context, project *models.
 		Exec(ctx)
 	return err
 }
-func (pr *ProjectRepository) SoftDeleteProject(ctx context.Context, projectID string) error {
-	_, err := database.DB.NewUpdate().
-		Model(&models.Project{}).
-		Set("deleted_at = ?", time.Now()).
+
+// SoftDeleteProject moves a project and all of its live secrets to the trash
+// in one transaction and returns the secrets deleted with it
+func (pr *ProjectRepository) SoftDeleteProject(ctx context.Context, projectID string) ([]models.Secret, error) {
+	var secrets []models.Secret
+	now := time.Now()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model(&models.Project{}).
+			Set("deleted_at = ?", now).
+			Where("project_id = ?", projectID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = tx.NewUpdate().
+			Model((*models.Secret)(nil)).
+			Set("deleted_at = ?", now).
+			Set("deleted_with_project = TRUE").
+			Where("project_id = ?", projectID).
+			Where("deleted_at IS NULL").
+			Returning("*").
+			Scan(ctx, &secrets)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// GetDeletedProjectByID loads a project that is in the trash
+func (pr *ProjectRepository) GetDeletedProjectByID(ctx context.Context, projectID string) (*models.Project, error) {
+	var project models.Project
+	err := database.DB.NewSelect().
+		Model(&project).
 		Where("project_id = ?", projectID).
+		Where("deleted_at IS NOT NULL").
+		Scan(ctx)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+func (pr *ProjectRepository) GetDeletedProjectsByUserID(ctx context.Context, ownership ProjectOwnership) ([]models.Project, error) {
+	var projects []models.Project
+
+	err := database.DB.NewSelect().
+		Model(&projects).
+		WhereGroup(" AND ", ownedBy(ownership, " AND role = 'owner'")).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// GetProjectsByOrg lists the live projects of an organization, whoever owns them within it
+func (pr *ProjectRepository) GetProjectsByOrg(ctx context.Context, orgID string) ([]models.Project, error) {
+	var projects []models.Project
+
+	err := database.DB.NewSelect().
+		Model(&projects).
+		Where("org_id = ?", orgID).
+		Where("deleted_at IS NULL").
+		Order("created_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// CountProjectsByOwner counts the projects, trashed ones included, owned by a team or org
+func (pr *ProjectRepository) CountProjectsByOwner(ctx context.Context, ownerType string, ownerID string) (int, error) {
+	return database.DB.NewSelect().
+		Model((*models.Project)(nil)).
+		Where("owner_type = ?", ownerType).
+		Where("owner_id = ?", ownerID).
+		Count(ctx)
+}
+
+// TransferProject stores a new owner of a live project
+func (pr *ProjectRepository) TransferProject(ctx context.Context, project *models.Project) error {
+	_, err := database.DB.NewUpdate().
+		Model(project).
+		Column("user_id", "owner_type", "owner_id", "org_id", "updated_at").
+		Where("project_id = ?", project.ID).
+		Where("deleted_at IS NULL").
 		Exec(ctx)
 	return err
 }
+
+// GetProjectByName finds a live project with the given name owned by a user,
+// team or org
+func (pr *ProjectRepository) GetProjectByName(ctx context.Context, ownerType string, ownerID string, name string) (*models.Project, error) {
+	var project models.Project
+	query := database.DB.NewSelect().
+		Model(&project).
+		Where("owner_type = ?", ownerType)
+	if ownerType == "user" {
+		// user owned projects keep their owner in user_id
+		query = query.Where("user_id = ?", ownerID)
+	} else {
+		query = query.Where("owner_id = ?", ownerID)
+	}
+	err := query.
+		Where("project_name = ?", name).
+		Where("deleted_at IS NULL").
+		Limit(1).
+		Scan(ctx)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &project, nil
+}
+
+// RestoreProject takes a project out of the trash together with exactly the
+// secrets that were deleted with it, and returns those secrets
+func (pr *ProjectRepository) RestoreProject(ctx context.Context, projectID string) ([]models.Secret, error) {
+	var secrets []models.Secret
+	now := time.Now()
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewUpdate().
+			Model(&models.Project{}).
+			Set("deleted_at = NULL").
+			Set("updated_at = ?", now).
+			Where("project_id = ?", projectID).
+			Where("deleted_at IS NOT NULL").
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = tx.NewUpdate().
+			Model((*models.Secret)(nil)).
+			Set("deleted_at = NULL").
+			Set("deleted_with_project = FALSE").
+			Set("updated_at = ?", now).
+			Where("project_id = ?", projectID).
+			Where("deleted_with_project = TRUE").
+			Returning("*").
+			Scan(ctx, &secrets)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// PurgeProject permanently deletes a project from the trash with all of its
+// secrets and versions in one transaction, and returns the purged secrets
+func (pr *ProjectRepository) PurgeProject(ctx context.Context, projectID string) ([]models.Secret, error) {
+	var secrets []models.Secret
+
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		err := tx.NewDelete().
+			Model((*models.Secret)(nil)).
+			Where("project_id = ?", projectID).
+			Returning("*").
+			Scan(ctx, &secrets)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.ProjectMember)(nil)).
+			Where("project_id = ?", projectID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		// service accounts, their tokens, access grants, break-glass sessions,
+		// rotation policies, leases, webhooks and notification channels cannot
+		// outlive the project
+		for _, model := range []any{
+			(*models.ServiceToken)(nil), (*models.ServiceAccount)(nil), (*models.AccessGrant)(nil), (*models.BreakGlassSession)(nil),
+			(*models.RotationPolicy)(nil), (*models.Lease)(nil), (*models.WebhookDelivery)(nil), (*models.WebhookSubscription)(nil),
+			(*models.NotificationChannel)(nil), (*models.NotificationRecord)(nil),
+		} {
+			_, err = tx.NewDelete().
+				Model(model).
+				Where("project_id = ?", projectID).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.ChangeRequestComment)(nil)).
+			Where("cr_id IN (SELECT cr_id FROM change_requests WHERE project_id = ?)", projectID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = tx.NewDelete().
+			Model((*models.ChangeRequest)(nil)).
+			Where("project_id = ?", projectID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.Project)(nil)).
+			Where("project_id = ?", projectID).
+			Where("deleted_at IS NOT NULL").
+			Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
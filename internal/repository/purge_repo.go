@@ -3,9 +3,13 @@ package repository
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
 )
 
 type PurgeRepository struct{}
@@ -19,22 +23,175 @@ func (r *PurgeRepository) PurgeOldData(ctx context.Context, olderThan time.Durat
 
 	threshold := time.Now().Add(-olderThan)
 
-	_, err := database.DB.NewDelete().
-		TableExpr("secrets").
-		Where("deleted_at IS NOT NULL").
-		Where("deleted_at < ?", threshold).
+	// secrets go together with their projects so none are left orphaned
+	err := database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var secrets []models.Secret
+		err := tx.NewSelect().
+			Model(&secrets).
+			Column("secret_id", "project_id", "secret_version").
+			WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+				return q.
+					WhereOr("deleted_at IS NOT NULL AND deleted_at < ?", threshold).
+					WhereOr("project_id IN (SELECT project_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < ?)", threshold)
+			}).
+			Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to find secrets to purge: %w", err)
+		}
+
+		var projects []models.Project
+		err = tx.NewSelect().
+			Model(&projects).
+			Column("project_id").
+			Where("deleted_at IS NOT NULL").
+			Where("deleted_at < ?", threshold).
+			Scan(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to find projects to purge: %w", err)
+		}
+
+		// the audit entries are written with the deletion, so a purge is never unrecorded
+		now := time.Now()
+		var entries []models.AuditLog
+		for _, secret := range secrets {
+			message := "Secret permanently deleted by the purge job (version " + strconv.Itoa(secret.Version) + ")"
+			entries = append(entries, models.AuditLog{
+				ProjectID: secret.ProjectID,
+				SecretID:  secret.ID,
+				Action:    "PURGE_SECRET",
+				Message:   &message,
+				Timestamp: now,
+			})
+		}
+		for _, project := range projects {
+			message := "Project permanently deleted by the purge job"
+			entries = append(entries, models.AuditLog{
+				ProjectID: project.ID,
+				Action:    "PURGE_PROJECT",
+				Message:   &message,
+				Timestamp: now,
+			})
+		}
+		if len(entries) > 0 {
+			if _, err := tx.NewInsert().Model(&entries).Exec(ctx); err != nil {
+				return fmt.Errorf("failed to audit purge: %w", err)
+			}
+		}
+
+		if len(secrets) > 0 {
+			ids := make([]uuid.UUID, 0, len(secrets))
+			for _, secret := range secrets {
+				ids = append(ids, secret.ID)
+			}
+			for _, table := range []string{"rotation_policies", "leases", "notification_records"} {
+				_, err = tx.NewDelete().
+					TableExpr(table).
+					Where("secret_id IN (?)", bun.In(ids)).
+					Exec(ctx)
+				if err != nil {
+					return fmt.Errorf("failed to purge %s: %w", table, err)
+				}
+			}
+			_, err = tx.NewDelete().
+				TableExpr("secrets").
+				Where("secret_id IN (?)", bun.In(ids)).
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to purge secrets: %w", err)
+			}
+		}
+
+		_, err = tx.NewDelete().
+			TableExpr("project_members").
+			Where("project_id IN (SELECT project_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < ?)", threshold).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to purge project members: %w", err)
+		}
+
+		for _, table := range []string{
+			"service_tokens", "service_accounts", "access_grants", "break_glass_sessions",
+			"rotation_policies", "leases", "webhook_deliveries", "webhook_subscriptions",
+			"notification_channels", "notification_records",
+		} {
+			_, err = tx.NewDelete().
+				TableExpr(table).
+				Where("project_id IN (SELECT project_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < ?)", threshold).
+				Exec(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to purge %s: %w", table, err)
+			}
+		}
+
+		_, err = tx.NewDelete().
+			TableExpr("change_request_comments").
+			Where("cr_id IN (SELECT cr_id FROM change_requests WHERE project_id IN (SELECT project_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < ?))", threshold).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to purge change request comments: %w", err)
+		}
+
+		_, err = tx.NewDelete().
+			TableExpr("change_requests").
+			Where("project_id IN (SELECT project_id FROM projects WHERE deleted_at IS NOT NULL AND deleted_at < ?)", threshold).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to purge change requests: %w", err)
+		}
+
+		if len(projects) == 0 {
+			return nil
+		}
+		projectIDs := make([]uuid.UUID, 0, len(projects))
+		for _, project := range projects {
+			projectIDs = append(projectIDs, project.ID)
+		}
+		_, err = tx.NewDelete().
+			TableExpr("projects").
+			Where("project_id IN (?)", bun.In(projectIDs)).
+			Exec(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to purge projects: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// used tokens are kept until then so reuse can still be audited
+	_, err = database.DB.NewDelete().
+		TableExpr("wrapped_tokens").
+		Where("expires_at < ?", threshold).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to purge wrapped tokens: %w", err)
+	}
+
+	_, err = database.DB.NewDelete().
+		TableExpr("shares").
+		Where("expires_at < ?", threshold).
+		Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to purge shares: %w", err)
+	}
+
+	_, err = database.DB.NewDelete().
+		TableExpr("service_tokens").
+		WhereOr("expires_at < ?", threshold).
+		WhereOr("revoked_at < ?", threshold).
 		Exec(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to purge secrets: %w", err)
+		return fmt.Errorf("failed to purge service tokens: %w", err)
 	}
 
+	// used up secret IDs are deleted on login, expired ones are left for this
 	_, err = database.DB.NewDelete().
-		TableExpr("projects").
-		Where("deleted_at IS NOT NULL").
-		Where("deleted_at < ?", threshold).
+		TableExpr("approle_secret_ids").
+		Where("expires_at < ?", threshold).
 		Exec(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to purge projects: %w", err)
+		return fmt.Errorf("failed to purge approle secret ids: %w", err)
 	}
 
 	return nil
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type RotationRepository struct{}
+
+func NewRotationRepository() *RotationRepository {
+	return &RotationRepository{}
+}
+
+// UpsertPolicy creates the rotation policy of a secret or replaces the existing one
+func (rr *RotationRepository) UpsertPolicy(ctx context.Context, policy *models.RotationPolicy) error {
+	_, err := database.DB.NewInsert().
+		Model(policy).
+		On("CONFLICT (secret_id) DO UPDATE").
+		Set("interval_hours = EXCLUDED.interval_hours").
+		Set("cron_expr = EXCLUDED.cron_expr").
+		Set("rotator = EXCLUDED.rotator").
+		Set("generate = EXCLUDED.generate").
+		Set("webhook_url = EXCLUDED.webhook_url").
+		Set("webhook_secret = EXCLUDED.webhook_secret").
+		Set("enabled = EXCLUDED.enabled").
+		Set("next_run_at = EXCLUDED.next_run_at").
+		Set("failure_count = 0").
+		Set("last_error = NULL").
+		Set("updated_at = EXCLUDED.updated_at").
+		Returning("*").
+		Exec(ctx)
+	return err
+}
+
+func (rr *RotationRepository) GetPolicyBySecretID(ctx context.Context, secretID string) (*models.RotationPolicy, error) {
+	var policy models.RotationPolicy
+	err := database.DB.NewSelect().
+		Model(&policy).
+		Where("secret_id = ?", secretID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (rr *RotationRepository) UpdatePolicy(ctx context.Context, policy *models.RotationPolicy) error {
+	_, err := database.DB.NewUpdate().
+		Model(policy).
+		Column("enabled", "next_run_at", "last_run_at", "last_error", "failure_count", "updated_at").
+		Where("policy_id = ?", policy.ID).
+		Exec(ctx)
+	return err
+}
+
+func (rr *RotationRepository) DeletePolicy(ctx context.Context, secretID string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.RotationPolicy)(nil)).
+		Where("secret_id = ?", secretID).
+		Exec(ctx)
+	return err
+}
+
+// ClaimDuePolicies picks up to limit enabled policies whose next run is due and
+// pushes their next run forward by claimFor, so concurrent schedulers (or a
+// crashed run) never rotate the same secret twice in a row.
+func (rr *RotationRepository) ClaimDuePolicies(ctx context.Context, now time.Time, claimFor time.Duration, limit int) ([]models.RotationPolicy, error) {
+	var policies []models.RotationPolicy
+
+	due := database.DB.NewSelect().
+		Model((*models.RotationPolicy)(nil)).
+		Column("policy_id").
+		Where("enabled = TRUE").
+		Where("next_run_at <= ?", now).
+		Order("next_run_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+
+	err := database.DB.NewUpdate().
+		Model((*models.RotationPolicy)(nil)).
+		Set("next_run_at = ?", now.Add(claimFor)).
+		Where("policy_id IN (?)", due).
+		Returning("*").
+		Scan(ctx, &policies)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return policies, nil
+}
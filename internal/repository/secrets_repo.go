@@ -7,6 +7,7 @@ import (
 
 	"github.com/akansha204/cryptex-secretservice/internal/database"
 	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/uptrace/bun"
 )
 
 type SecretRepository struct{}
@@ -40,7 +41,7 @@ func (sr *SecretRepository) GetSecretByID(ctx context.Context, secretID string)
 func (sr *SecretRepository) UpdateSecret(ctx context.Context, secret *models.Secret) error {
 	_, err := database.DB.NewUpdate().
 		Model(secret).
-		Column("s_name", "s_value", "secret_version", "updated_at", "revoked", "ttl", "expires_at", "deleted_at").
+		Column("s_name", "s_value", "secret_version", "updated_at", "revoked", "ttl_seconds", "not_before", "expires_at", "lease_ttl_seconds", "lease_max_ttl_seconds", "sensitive", "allowed_cidrs", "deleted_at").
 		Where("secret_id = ?", secret.ID).
 		Where("deleted_at IS NULL").
 		Exec(ctx)
@@ -54,6 +55,110 @@ func (sr *SecretRepository) SoftDeleteSecret(ctx context.Context, secretID strin
 		Exec(ctx)
 	return err
 }
+
+// ConsumeRead atomically takes one read from a read-limited secret and returns
+// the reads left. ok is false when no read was left, so of two concurrent
+// readers only one can get the last read.
+func (sr *SecretRepository) ConsumeRead(ctx context.Context, secretID string) (remaining int, ok bool, err error) {
+	var left []int
+	err = database.DB.NewUpdate().
+		Model((*models.Secret)(nil)).
+		Set("reads_remaining = reads_remaining - 1").
+		Where("secret_id = ?", secretID).
+		Where("reads_remaining > 0").
+		Where("deleted_at IS NULL").
+		Returning("reads_remaining").
+		Scan(ctx, &left)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, false, err
+	}
+	if len(left) == 0 {
+		return 0, false, nil
+	}
+	return left[0], true, nil
+}
+
+// DestroySecret permanently deletes a secret, skipping the soft-delete window,
+// together with its rotation policy, leases and notification records
+func (sr *SecretRepository) DestroySecret(ctx context.Context, secretID string) error {
+	return database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		for _, model := range []any{(*models.RotationPolicy)(nil), (*models.Lease)(nil), (*models.NotificationRecord)(nil)} {
+			_, err := tx.NewDelete().
+				Model(model).
+				Where("secret_id = ?", secretID).
+				Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := tx.NewDelete().
+			Model((*models.Secret)(nil)).
+			Where("secret_id = ?", secretID).
+			Exec(ctx)
+		return err
+	})
+}
+
+// GetSecretsByProject lists the live secrets of a project, every version included
+func (sr *SecretRepository) GetSecretsByProject(ctx context.Context, projectID string) ([]models.Secret, error) {
+	var secrets []models.Secret
+
+	err := database.DB.NewSelect().
+		Model(&secrets).
+		Where("project_id = ?", projectID).
+		Where("deleted_at IS NULL").
+		Order("s_name ASC", "secret_version DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+
+// GetDeletedSecretByID loads a secret that is in the trash
+func (sr *SecretRepository) GetDeletedSecretByID(ctx context.Context, secretID string) (*models.Secret, error) {
+	var secret models.Secret
+	err := database.DB.NewSelect().
+		Model(&secret).
+		Where("secret_id = ?", secretID).
+		Where("deleted_at IS NOT NULL").
+		Scan(ctx)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &secret, nil
+}
+func (sr *SecretRepository) GetDeletedSecretsByProject(ctx context.Context, projectID string) ([]models.Secret, error) {
+	var secrets []models.Secret
+
+	err := database.DB.NewSelect().
+		Model(&secrets).
+		Where("project_id = ?", projectID).
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+	return secrets, nil
+}
+func (sr *SecretRepository) RestoreSecret(ctx context.Context, secretID string) error {
+	_, err := database.DB.NewUpdate().
+		Model(&models.Secret{}).
+		Set("deleted_at = NULL").
+		Set("deleted_with_project = FALSE").
+		Set("updated_at = ?", time.Now()).
+		Where("secret_id = ?", secretID).
+		Where("deleted_at IS NOT NULL").
+		Exec(ctx)
+	return err
+}
 func (sr *SecretRepository) GetLatestVersion(ctx context.Context, projectID, name string) (*models.Secret, error) {
 	var secret models.Secret
 
@@ -76,3 +181,23 @@ func (sr *SecretRepository) GetLatestVersion(ctx context.Context, projectID, nam
 
 	return &secret, nil
 }
+
+// GetSecretsExpiringBetween returns live, unrevoked secrets whose expiry falls in (from, to]
+func (sr *SecretRepository) GetSecretsExpiringBetween(ctx context.Context, from, to time.Time) ([]models.Secret, error) {
+	var secrets []models.Secret
+
+	err := database.DB.NewSelect().
+		Model(&secrets).
+		Where("expires_at > ?", from).
+		Where("expires_at <= ?", to).
+		Where("revoked = FALSE").
+		Where("deleted_at IS NULL").
+		Order("expires_at ASC").
+		Scan(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return secrets, nil
+}
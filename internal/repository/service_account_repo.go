@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/uptrace/bun"
+)
+
+// a token's last use is written at most once per this interval
+const tokenTouchInterval = time.Minute
+
+type ServiceAccountRepository struct{}
+
+func NewServiceAccountRepository() *ServiceAccountRepository {
+	return &ServiceAccountRepository{}
+}
+
+func (sr *ServiceAccountRepository) CreateServiceAccount(ctx context.Context, account *models.ServiceAccount) error {
+	_, err := database.DB.NewInsert().
+		Model(account).
+		Exec(ctx)
+	return err
+}
+
+func (sr *ServiceAccountRepository) GetServiceAccountByID(ctx context.Context, accountID string) (*models.ServiceAccount, error) {
+	var account models.ServiceAccount
+	err := database.DB.NewSelect().
+		Model(&account).
+		Where("sa_id = ?", accountID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (sr *ServiceAccountRepository) GetServiceAccountsByProject(ctx context.Context, projectID string) ([]models.ServiceAccount, error) {
+	var accounts []models.ServiceAccount
+	err := database.DB.NewSelect().
+		Model(&accounts).
+		Where("project_id = ?", projectID).
+		Order("sa_name ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return accounts, nil
+}
+
+// DeleteServiceAccount deletes a service account with its tokens and policy attachments
+func (sr *ServiceAccountRepository) DeleteServiceAccount(ctx context.Context, accountID string) error {
+	return database.DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		_, err := tx.NewDelete().
+			Model((*models.ServiceToken)(nil)).
+			Where("sa_id = ?", accountID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.PolicyAttachment)(nil)).
+			Where("principal_type = 'service_account'").
+			Where("principal_id = ?", accountID).
+			Exec(ctx)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.NewDelete().
+			Model((*models.ServiceAccount)(nil)).
+			Where("sa_id = ?", accountID).
+			Exec(ctx)
+		return err
+	})
+}
+
+func (sr *ServiceAccountRepository) CreateToken(ctx context.Context, token *models.ServiceToken) error {
+	_, err := database.DB.NewInsert().
+		Model(token).
+		Exec(ctx)
+	return err
+}
+
+func (sr *ServiceAccountRepository) GetTokenByHash(ctx context.Context, hash string) (*models.ServiceToken, error) {
+	var token models.ServiceToken
+	err := database.DB.NewSelect().
+		Model(&token).
+		Where("token_hash = ?", hash).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (sr *ServiceAccountRepository) GetTokensByServiceAccount(ctx context.Context, accountID string) ([]models.ServiceToken, error) {
+	var tokens []models.ServiceToken
+	err := database.DB.NewSelect().
+		Model(&tokens).
+		Where("sa_id = ?", accountID).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken revokes a live token of a service account; it reports false when there was none
+func (sr *ServiceAccountRepository) RevokeToken(ctx context.Context, accountID string, tokenID string) (bool, error) {
+	res, err := database.DB.NewUpdate().
+		Model((*models.ServiceToken)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Where("token_id = ?", tokenID).
+		Where("sa_id = ?", accountID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// TouchToken records the last use of a token, skipping the write when it was recorded recently
+func (sr *ServiceAccountRepository) TouchToken(ctx context.Context, tokenID string, ip string) error {
+	now := time.Now()
+	_, err := database.DB.NewUpdate().
+		Model((*models.ServiceToken)(nil)).
+		Set("last_used_at = ?", now).
+		Set("last_used_ip = ?", ip).
+		Where("token_id = ?", tokenID).
+		WhereGroup(" AND ", func(q *bun.UpdateQuery) *bun.UpdateQuery {
+			return q.
+				Where("last_used_at IS NULL").
+				WhereOr("last_used_at < ?", now.Add(-tokenTouchInterval)).
+				WhereOr("last_used_ip <> ?", ip)
+		}).
+		Exec(ctx)
+	return err
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type ShareRepository struct{}
+
+func NewShareRepository() *ShareRepository {
+	return &ShareRepository{}
+}
+
+func (sr *ShareRepository) CreateShare(ctx context.Context, share *models.Share) error {
+	_, err := database.DB.NewInsert().
+		Model(share).
+		Exec(ctx)
+	return err
+}
+
+func (sr *ShareRepository) GetShareByID(ctx context.Context, shareID string) (*models.Share, error) {
+	var share models.Share
+	err := database.DB.NewSelect().
+		Model(&share).
+		Where("share_id = ?", shareID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (sr *ShareRepository) GetSharesByProject(ctx context.Context, projectID string) ([]models.Share, error) {
+	var shares []models.Share
+	err := database.DB.NewSelect().
+		Model(&shares).
+		Where("project_id = ?", projectID).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return shares, nil
+}
+
+// ConsumeView atomically counts one view of a live share and returns the
+// updated share, or nil when it is used up, expired or revoked
+func (sr *ShareRepository) ConsumeView(ctx context.Context, shareID string, now time.Time) (*models.Share, error) {
+	var shares []models.Share
+	err := database.DB.NewUpdate().
+		Model((*models.Share)(nil)).
+		Set("views = views + 1").
+		Where("share_id = ?", shareID).
+		Where("views < max_views").
+		Where("revoked_at IS NULL").
+		Where("expires_at > ?", now).
+		Returning("*").
+		Scan(ctx, &shares)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if len(shares) == 0 {
+		return nil, nil
+	}
+	return &shares[0], nil
+}
+
+// RevokeShare revokes a live share and drops its ciphertext
+func (sr *ShareRepository) RevokeShare(ctx context.Context, shareID string) (bool, error) {
+	res, err := database.DB.NewUpdate().
+		Model((*models.Share)(nil)).
+		Set("revoked_at = ?", time.Now()).
+		Set("ciphertext = ''").
+		Where("share_id = ?", shareID).
+		Where("revoked_at IS NULL").
+		Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ScrubShare drops the ciphertext of a share that can no longer be viewed
+func (sr *ShareRepository) ScrubShare(ctx context.Context, shareID string) error {
+	_, err := database.DB.NewUpdate().
+		Model((*models.Share)(nil)).
+		Set("ciphertext = ''").
+		Where("share_id = ?", shareID).
+		Exec(ctx)
+	return err
+}
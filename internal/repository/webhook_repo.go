@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type WebhookRepository struct{}
+
+func NewWebhookRepository() *WebhookRepository {
+	return &WebhookRepository{}
+}
+
+func (wr *WebhookRepository) CreateSubscription(ctx context.Context, sub *models.WebhookSubscription) error {
+	_, err := database.DB.NewInsert().
+		Model(sub).
+		Exec(ctx)
+	return err
+}
+
+func (wr *WebhookRepository) GetSubscriptionByID(ctx context.Context, subscriptionID string) (*models.WebhookSubscription, error) {
+	var sub models.WebhookSubscription
+	err := database.DB.NewSelect().
+		Model(&sub).
+		Where("subscription_id = ?", subscriptionID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (wr *WebhookRepository) GetSubscriptionsByProject(ctx context.Context, projectID string) ([]models.WebhookSubscription, error) {
+	var subs []models.WebhookSubscription
+	err := database.DB.NewSelect().
+		Model(&subs).
+		Where("project_id = ?", projectID).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return subs, nil
+}
+
+func (wr *WebhookRepository) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	_, err := database.DB.NewDelete().
+		Model((*models.WebhookSubscription)(nil)).
+		Where("subscription_id = ?", subscriptionID).
+		Exec(ctx)
+	return err
+}
+
+func (wr *WebhookRepository) EnqueueDeliveries(ctx context.Context, deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	_, err := database.DB.NewInsert().
+		Model(&deliveries).
+		Exec(ctx)
+	return err
+}
+
+func (wr *WebhookRepository) GetDeliveryByID(ctx context.Context, deliveryID string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := database.DB.NewSelect().
+		Model(&delivery).
+		Where("delivery_id = ?", deliveryID).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetDeliveriesByProject lists the newest deliveries of a project, optionally filtered by status
+func (wr *WebhookRepository) GetDeliveriesByProject(ctx context.Context, projectID string, status string, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	q := database.DB.NewSelect().
+		Model(&deliveries).
+		Where("project_id = ?", projectID)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+
+	err := q.Order("created_at DESC").
+		Limit(limit).
+		Scan(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (wr *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	_, err := database.DB.NewUpdate().
+		Model(delivery).
+		Column("status", "attempts", "next_attempt_at", "last_error", "last_status_code", "delivered_at").
+		Where("delivery_id = ?", delivery.ID).
+		Exec(ctx)
+	return err
+}
+
+// ClaimDueDeliveries picks pending deliveries that are due and hides them from
+// other dispatchers for claimFor while they are being sent.
+func (wr *WebhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, claimFor time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+
+	due := database.DB.NewSelect().
+		Model((*models.WebhookDelivery)(nil)).
+		Column("delivery_id").
+		Where("status = 'pending'").
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+
+	err := database.DB.NewUpdate().
+		Model((*models.WebhookDelivery)(nil)).
+		Set("next_attempt_at = ?", now.Add(claimFor)).
+		Where("delivery_id IN (?)", due).
+		Returning("*").
+		Scan(ctx, &deliveries)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/database"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+)
+
+type WrappingRepository struct{}
+
+func NewWrappingRepository() *WrappingRepository {
+	return &WrappingRepository{}
+}
+
+func (wr *WrappingRepository) CreateToken(ctx context.Context, token *models.WrappedToken) error {
+	_, err := database.DB.NewInsert().
+		Model(token).
+		Exec(ctx)
+	return err
+}
+
+func (wr *WrappingRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.WrappedToken, error) {
+	var token models.WrappedToken
+	err := database.DB.NewSelect().
+		Model(&token).
+		Where("token_hash = ?", tokenHash).
+		Scan(ctx)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// ClaimToken marks a live token as unwrapped and returns it with its value.
+// It returns nil when the token was already unwrapped or has expired, so only
+// one of several concurrent unwraps can succeed.
+func (wr *WrappingRepository) ClaimToken(ctx context.Context, tokenHash string, now time.Time) (*models.WrappedToken, error) {
+	var tokens []models.WrappedToken
+	err := database.DB.NewUpdate().
+		Model((*models.WrappedToken)(nil)).
+		Set("unwrapped_at = ?", now).
+		Where("token_hash = ?", tokenHash).
+		Where("unwrapped_at IS NULL").
+		Where("expires_at > ?", now).
+		Returning("*").
+		Scan(ctx, &tokens)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	return &tokens[0], nil
+}
+
+// ScrubToken drops the encrypted value of a token that was unwrapped
+func (wr *WrappingRepository) ScrubToken(ctx context.Context, tokenID string) error {
+	_, err := database.DB.NewUpdate().
+		Model((*models.WrappedToken)(nil)).
+		Set("s_value = ''").
+		Where("token_id = ?", tokenID).
+		Exec(ctx)
+	return err
+}
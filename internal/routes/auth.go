@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/middlewares"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/services"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// authMiddleware authenticates people the way AUTH_MODE says: through the
+// gateway (the default), with JWTs of an identity provider, or both. Machines
+// always use a service account token or the token of an AppRole login, or a
+// client certificate when mTLS is on.
+func authMiddleware(serviceAccountService *services.ServiceAccountService, appRoleService *services.AppRoleService) fiber.Handler {
+	appRoleKey, err := utils.AppRoleSigningKey()
+	if err != nil {
+		log.Fatal("Error loading AppRole signing key:", err)
+	}
+
+	bearers := []middlewares.BearerAuth{
+		{
+			Match:   middlewares.HasPrefix(services.ServiceTokenPrefix),
+			Handler: middlewares.TokenAuth(serviceAccountService, services.ServiceTokenPrefix),
+		},
+		{
+			Match:   middlewares.IssuedBy(utils.AppRoleIssuer),
+			Handler: middlewares.AppRoleAuth(appRoleKey, appRoleService),
+		},
+	}
+
+	var fallback fiber.Handler
+	mode := strings.ToLower(os.Getenv("AUTH_MODE"))
+	switch mode {
+	case "", "gateway", "both":
+		fallback = gatewayAuth()
+	case "jwt":
+	default:
+		log.Fatal("AUTH_MODE must be gateway, jwt or both, got " + mode)
+	}
+
+	if mode == "jwt" || mode == "both" {
+		verifier, err := utils.LoadJWTVerifier()
+		if err != nil {
+			log.Fatal("Error loading JWT verifier:", err)
+		}
+		if verifier.Issuer() == utils.AppRoleIssuer {
+			log.Fatal("JWT_ISSUER must differ from the AppRole issuer " + utils.AppRoleIssuer)
+		}
+		bearers = append(bearers, middlewares.BearerAuth{
+			Match:   middlewares.IssuedBy(verifier.Issuer()),
+			Handler: middlewares.JWTAuth(verifier),
+		})
+	}
+
+	// with mTLS, requests without a bearer token can also authenticate with their client certificate
+	if os.Getenv("TLS_CLIENT_CA_FILE") != "" {
+		fallback = middlewares.ClientCertAuth(fallback)
+	}
+	return middlewares.Authenticate(fallback, bearers...)
+}
+
+// gatewayAuth verifies the gateway's identity headers with GATEWAY_HMAC_KEYS,
+// accepting timestamps GATEWAY_MAX_SKEW (default 60s) off. Unsigned headers
+// are only accepted with GATEWAY_ALLOW_UNSIGNED=true, for local development.
+func gatewayAuth() fiber.Handler {
+	keys, err := utils.LoadGatewayKeys()
+	if err != nil {
+		log.Fatal("Error loading gateway keys:", err)
+	}
+	if keys == nil {
+		if os.Getenv("GATEWAY_ALLOW_UNSIGNED") != "true" {
+			log.Fatal("GATEWAY_HMAC_KEYS is required to trust gateway headers (set GATEWAY_ALLOW_UNSIGNED=true for local development)")
+		}
+		log.Println("[AUTH WARNING] gateway headers are not signed; anyone who can reach the service can act as any user")
+	}
+
+	maxSkew := time.Minute
+	if raw := os.Getenv("GATEWAY_MAX_SKEW"); raw != "" {
+		maxSkew, err = utils.ParseDuration(raw)
+		if err != nil || maxSkew <= 0 {
+			log.Fatal("GATEWAY_MAX_SKEW must be a positive duration")
+		}
+	}
+	return middlewares.GatewayAuth(keys, maxSkew, repository.NewGatewayNonceRepository())
+}
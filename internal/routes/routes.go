@@ -11,30 +11,201 @@ import (
 func SetupRoutes(app *fiber.App) {
 
 	auditRepo := repository.NewAuditRepository()
+	breakGlassRepo := repository.NewBreakGlassRepository()
 	auditService := services.NewAuditService(auditRepo)
 
 	projectRepo := repository.NewProjectRepository()
-	projectService := services.NewProjectService(projectRepo, auditService)
+	memberRepo := repository.NewMemberRepository()
+	policyRepo := repository.NewPolicyRepository()
+	orgRepo := repository.NewOrgRepository()
+	grantRepo := repository.NewAccessGrantRepository()
+	authorizer := services.NewAuthorizer(projectRepo, memberRepo, policyRepo, orgRepo, grantRepo, breakGlassRepo)
+
+	accessGrantService := services.NewAccessGrantService(grantRepo, authorizer, auditService)
+	accessGrantController := controllers.NewAccessGrantController(accessGrantService)
+
+	serviceAccountRepo := repository.NewServiceAccountRepository()
+	serviceAccountService := services.NewServiceAccountService(serviceAccountRepo, authorizer, auditService)
+	serviceAccountController := controllers.NewServiceAccountController(serviceAccountService)
+
+	appRoleRepo := repository.NewAppRoleRepository()
+	appRoleService := services.NewAppRoleService(appRoleRepo, authorizer, auditService)
+	appRoleController := controllers.NewAppRoleController(appRoleService)
+
+	orgService := services.NewOrgService(orgRepo, projectRepo, authorizer, auditService)
+	orgController := controllers.NewOrgController(orgService)
+
+	policyService := services.NewPolicyService(policyRepo, authorizer, auditService)
+	policyController := controllers.NewPolicyController(policyService)
+
+	memberService := services.NewMemberService(memberRepo, authorizer, auditService)
+	memberController := controllers.NewMemberController(memberService)
+
+	webhookRepo := repository.NewWebhookRepository()
+	webhookService := services.NewWebhookService(webhookRepo, authorizer, auditService)
+	webhookController := controllers.NewWebhookController(webhookService)
+
+	projectService := services.NewProjectService(projectRepo, authorizer, auditService, webhookService)
 	projectController := controllers.NewProjectController(projectService)
 
 	secretRepo := repository.NewSecretRepository()
-	secretService := services.NewSecretService(secretRepo, projectRepo, auditService)
+
+	notificationRepo := repository.NewNotificationRepository()
+	notificationService := services.NewNotificationService(notificationRepo, authorizer, secretRepo, auditService)
+	notificationController := controllers.NewNotificationController(notificationService)
+
+	leaseRepo := repository.NewLeaseRepository()
+	leaseService := services.NewLeaseService(leaseRepo, secretRepo, authorizer, auditService, webhookService)
+	leaseController := controllers.NewLeaseController(leaseService)
+
+	mfaRepo := repository.NewMFARepository()
+	mfaService := services.NewMFAService(mfaRepo, auditService)
+	mfaController := controllers.NewMFAController(mfaService)
+
+	changeRequestRepo := repository.NewChangeRequestRepository()
+	secretService := services.NewSecretService(secretRepo, authorizer, auditService, notificationService, webhookService, leaseService, mfaService, changeRequestRepo)
 	secretController := controllers.NewSecretController(secretService)
 
+	changeRequestService := services.NewChangeRequestService(changeRequestRepo, authorizer, secretService, auditService)
+	changeRequestController := controllers.NewChangeRequestController(changeRequestService)
+
+	wrappingRepo := repository.NewWrappingRepository()
+	wrappingService := services.NewWrappingService(wrappingRepo, secretService, auditService)
+	wrappingController := controllers.NewWrappingController(wrappingService)
+
+	shareRepo := repository.NewShareRepository()
+	shareService := services.NewShareService(shareRepo, authorizer, secretService, auditService)
+	shareController := controllers.NewShareController(shareService)
+
+	breakGlassService := services.NewBreakGlassService(breakGlassRepo, authorizer, auditService, notificationService)
+	breakGlassController := controllers.NewBreakGlassController(breakGlassService)
+
+	rotationRepo := repository.NewRotationRepository()
+	rotationService := services.NewRotationService(rotationRepo, secretRepo, authorizer, auditService, notificationService, webhookService)
+	rotationController := controllers.NewRotationController(rotationService)
+
 	api := app.Group("/api")
 
-	api.Post("/projects", middlewares.GatewayAuth(), projectController.CreateProject)
-	api.Get("/projects/:id", middlewares.GatewayAuth(), projectController.GetProject)
-	api.Get("/projects", middlewares.GatewayAuth(), projectController.GetUserProjects)
-	api.Put("/projects/:id", middlewares.GatewayAuth(), projectController.UpdateProject)
-	api.Delete("/projects/:id", middlewares.GatewayAuth(), projectController.DeleteProject)
+	auth := authMiddleware(serviceAccountService, appRoleService)
+
+	// JWTs with a scope claim only reach the routes their scopes cover
+	projectsRead := middlewares.RequireScope(middlewares.ScopeProjectsRead)
+	projectsAdmin := middlewares.RequireScope(middlewares.ScopeProjectsAdmin)
+	secretsRead := middlewares.RequireScope(middlewares.ScopeSecretsRead)
+	secretsWrite := middlewares.RequireScope(middlewares.ScopeSecretsWrite)
+
+	// the role ID and secret ID are the credentials
+	api.Post("/auth/approle/login", appRoleController.Login)
+
+	api.Get("/mfa/totp", auth, mfaController.GetTOTP)
+	api.Post("/mfa/totp", auth, mfaController.EnrollTOTP)
+	api.Post("/mfa/totp/confirm", auth, mfaController.ConfirmTOTP)
+	api.Delete("/mfa/totp", auth, mfaController.RemoveTOTP)
+
+	api.Post("/projects", auth, projectsAdmin, projectController.CreateProject)
+	api.Get("/projects/:id", auth, projectsRead, projectController.GetProject)
+	api.Get("/projects", auth, projectsRead, projectController.GetUserProjects)
+	api.Put("/projects/:id", auth, projectsAdmin, projectController.UpdateProject)
+	api.Delete("/projects/:id", auth, projectsAdmin, projectController.DeleteProject)
+	api.Post("/projects/:id/transfer", auth, projectsAdmin, projectController.TransferProject)
+
+	api.Post("/projects/:id/service-accounts", auth, projectsAdmin, serviceAccountController.CreateServiceAccount)
+	api.Get("/projects/:id/service-accounts", auth, projectsAdmin, serviceAccountController.ListServiceAccounts)
+	api.Delete("/projects/:id/service-accounts/:saId", auth, projectsAdmin, serviceAccountController.DeleteServiceAccount)
+	api.Post("/projects/:id/service-accounts/:saId/tokens", auth, projectsAdmin, serviceAccountController.CreateToken)
+	api.Get("/projects/:id/service-accounts/:saId/tokens", auth, projectsAdmin, serviceAccountController.ListTokens)
+	api.Delete("/projects/:id/service-accounts/:saId/tokens/:tokenId", auth, projectsAdmin, serviceAccountController.RevokeToken)
+
+	api.Post("/approles", auth, projectsAdmin, appRoleController.CreateRole)
+	api.Get("/approles", auth, projectsAdmin, appRoleController.ListRoles)
+	api.Get("/approles/:roleId", auth, projectsAdmin, appRoleController.GetRole)
+	api.Delete("/approles/:roleId", auth, projectsAdmin, appRoleController.DeleteRole)
+	api.Post("/approles/:roleId/secret-ids", auth, projectsAdmin, appRoleController.GenerateSecretID)
+	api.Get("/approles/:roleId/secret-ids", auth, projectsAdmin, appRoleController.ListSecretIDs)
+	api.Delete("/approles/:roleId/secret-ids/:accessor", auth, projectsAdmin, appRoleController.DestroySecretID)
+
+	api.Post("/orgs", auth, projectsAdmin, orgController.CreateOrg)
+	api.Get("/orgs", auth, projectsRead, orgController.ListOrgs)
+	api.Get("/orgs/:orgId", auth, projectsRead, orgController.GetOrg)
+	api.Get("/orgs/:orgId/projects", auth, projectsRead, orgController.ListProjects)
+	api.Post("/orgs/:orgId/teams", auth, projectsAdmin, orgController.CreateTeam)
+	api.Get("/orgs/:orgId/teams", auth, projectsRead, orgController.ListTeams)
+	api.Delete("/orgs/:orgId/teams/:teamId", auth, projectsAdmin, orgController.DeleteTeam)
+
+	api.Get("/trash/projects", auth, projectsRead, projectController.ListTrash)
+	api.Post("/trash/projects/:id/restore", auth, projectsAdmin, projectController.RestoreProject)
+	api.Delete("/trash/projects/:id", auth, projectsAdmin, projectController.PurgeProject)
+	api.Get("/projects/:projectId/trash", auth, secretsRead, secretController.ListTrash)
+	api.Post("/projects/:projectId/trash/:secretId/restore", auth, secretsWrite, secretController.RestoreSecret)
+	api.Delete("/projects/:projectId/trash/:secretId", auth, secretsWrite, secretController.PurgeSecret)
+
+	api.Get("/projects/:id/members", auth, projectsRead, memberController.ListMembers)
+	api.Post("/projects/:id/members", auth, projectsAdmin, memberController.AddMember)
+	api.Patch("/projects/:id/members/:userId", auth, projectsAdmin, memberController.UpdateMember)
+	api.Delete("/projects/:id/members/:userId", auth, projectsAdmin, memberController.RemoveMember)
+
+	api.Post("/policies/evaluate", auth, projectsRead, policyController.Evaluate)
+	api.Post("/policies", auth, projectsAdmin, policyController.CreatePolicy)
+	api.Get("/policies", auth, projectsAdmin, policyController.ListPolicies)
+	api.Get("/policies/:policyId", auth, projectsAdmin, policyController.GetPolicy)
+	api.Put("/policies/:policyId", auth, projectsAdmin, policyController.UpdatePolicy)
+	api.Delete("/policies/:policyId", auth, projectsAdmin, policyController.DeletePolicy)
+	api.Post("/policies/:policyId/attachments", auth, projectsAdmin, policyController.AttachPolicy)
+	api.Delete("/policies/:policyId/attachments/:attachmentId", auth, projectsAdmin, policyController.DetachPolicy)
+
+	api.Post("/projects/:id/notifications", auth, projectsAdmin, notificationController.CreateChannel)
+	api.Get("/projects/:id/notifications", auth, projectsRead, notificationController.ListChannels)
+	api.Delete("/projects/:id/notifications/:channelId", auth, projectsAdmin, notificationController.DeleteChannel)
+
+	api.Post("/projects/:id/webhooks", auth, projectsAdmin, webhookController.CreateWebhook)
+	api.Get("/projects/:id/webhooks", auth, projectsRead, webhookController.ListWebhooks)
+	api.Delete("/projects/:id/webhooks/:webhookId", auth, projectsAdmin, webhookController.DeleteWebhook)
+	api.Get("/projects/:id/webhooks/deliveries", auth, projectsRead, webhookController.ListDeliveries)
+	api.Post("/projects/:id/webhooks/deliveries/:deliveryId/retry", auth, projectsAdmin, webhookController.RetryDelivery)
+
+	api.Get("/projects/:id/leases", auth, secretsRead, leaseController.ListLeases)
+	api.Post("/projects/:id/leases/revoke-prefix", auth, secretsWrite, leaseController.RevokePrefix)
+	api.Put("/leases/renew", auth, secretsRead, leaseController.RenewLease)
+	api.Put("/leases/revoke", auth, secretsWrite, leaseController.RevokeLease)
+
+	api.Post("/projects/:id/break-glass", auth, projectsAdmin, breakGlassController.OpenSession)
+	api.Get("/projects/:id/break-glass", auth, projectsRead, breakGlassController.ListSessions)
+	api.Post("/projects/:id/break-glass/:sessionId/end", auth, projectsAdmin, breakGlassController.EndSession)
+
+	api.Post("/projects/:id/access-grants", auth, secretsRead, accessGrantController.RequestGrant)
+	api.Get("/projects/:id/access-grants", auth, secretsRead, accessGrantController.ListGrants)
+	api.Post("/projects/:id/access-grants/:grantId/approve", auth, secretsWrite, accessGrantController.ApproveGrant)
+	api.Post("/projects/:id/access-grants/:grantId/reject", auth, secretsWrite, accessGrantController.RejectGrant)
+	api.Post("/projects/:id/access-grants/:grantId/revoke", auth, secretsWrite, accessGrantController.RevokeGrant)
+
+	api.Get("/projects/:id/change-requests", auth, secretsRead, changeRequestController.ListChangeRequests)
+	api.Get("/projects/:id/change-requests/:crId", auth, secretsRead, changeRequestController.GetChangeRequest)
+	api.Post("/projects/:id/change-requests/:crId/approve", auth, secretsWrite, changeRequestController.ApproveChangeRequest)
+	api.Post("/projects/:id/change-requests/:crId/reject", auth, secretsWrite, changeRequestController.RejectChangeRequest)
+	api.Post("/projects/:id/change-requests/:crId/cancel", auth, secretsWrite, changeRequestController.CancelChangeRequest)
+	api.Post("/projects/:id/change-requests/:crId/comments", auth, secretsWrite, changeRequestController.CommentChangeRequest)
+
+	api.Post("/projects/:id/shares", auth, secretsWrite, shareController.CreateShare)
+	api.Get("/projects/:id/shares", auth, secretsRead, shareController.ListShares)
+	api.Delete("/projects/:id/shares/:shareId", auth, secretsWrite, shareController.RevokeShare)
+
+	// the wrapped token and the share link are the credentials, so these take no gateway auth
+	api.Post("/unwrap", wrappingController.Unwrap)
+	api.Post("/shares/:shareId/view", shareController.ViewShare)
+
+	secured := api.Group("/projects/:projectId/secrets", auth)
 
-	secured := api.Group("/projects/:projectId/secrets", middlewares.GatewayAuth())
+	secured.Post("/", secretsWrite, secretController.CreateSecret)
+	secured.Get("/", secretsRead, secretController.ListSecrets)
+	secured.Get("/:secretId", secretsRead, secretController.GetSecret)
+	secured.Patch("/:secretId", secretsWrite, secretController.UpdateSecret)
+	secured.Delete("/:secretId", secretsWrite, secretController.DeleteSecret)
+	secured.Patch("/:secretId/revoke", secretsWrite, secretController.RevokeSecret)
+	secured.Post("/:secretId/wrap", secretsRead, wrappingController.WrapSecret)
 
-	secured.Post("/", secretController.CreateSecret)
-	secured.Get("/:secretId", secretController.GetSecret)
-	secured.Patch("/:secretId", secretController.UpdateSecret)
-	secured.Delete("/:secretId", secretController.DeleteSecret)
-	secured.Patch("/:secretId/revoke", secretController.RevokeSecret)
+	secured.Put("/:secretId/rotation", secretsWrite, rotationController.SetPolicy)
+	secured.Get("/:secretId/rotation", secretsRead, rotationController.GetPolicy)
+	secured.Delete("/:secretId/rotation", secretsWrite, rotationController.DeletePolicy)
+	secured.Post("/:secretId/rotate", secretsWrite, rotationController.RotateNow)
 
 }
@@ -0,0 +1,694 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	RoleOwner        = "owner"
+	RoleAdmin        = "admin"
+	RoleWriter       = "writer"
+	RoleReader       = "reader"
+	RoleMetadataOnly = "metadata-only"
+)
+
+// Permission is something a user can do on a project or secret
+type Permission string
+
+const (
+	PermList    Permission = "list"    // see the project and secret metadata
+	PermRead    Permission = "read"    // read secret values
+	PermCreate  Permission = "create"  // create secrets
+	PermUpdate  Permission = "update"  // change secret values and settings, rotate
+	PermDelete  Permission = "delete"  // delete and restore secrets
+	PermRevoke  Permission = "revoke"  // revoke secrets, leases and shares
+	PermApprove Permission = "approve" // approve and reject change requests of other members
+	PermManage  Permission = "manage"  // project settings, webhooks, notifications, members
+	PermOwn     Permission = "own"     // delete, restore and purge the project, manage admins and owners
+)
+
+// CapDeny in a policy rule denies every permission on the matching paths
+const CapDeny = "deny"
+
+// CapAutoGrant in a policy rule approves just-in-time read grants on the
+// matching paths without waiting for an approver
+const CapAutoGrant = "auto-grant"
+
+// who can own a project
+const (
+	OwnerUser = "user"
+	OwnerTeam = "team"
+	OwnerOrg  = "org"
+)
+
+const (
+	PrincipalUser           = "user"
+	PrincipalGroup          = "group"
+	PrincipalServiceAccount = "service_account"
+	PrincipalCertificate    = "certificate"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleOwner:        {PermList, PermRead, PermCreate, PermUpdate, PermDelete, PermRevoke, PermApprove, PermManage, PermOwn},
+	RoleAdmin:        {PermList, PermRead, PermCreate, PermUpdate, PermDelete, PermRevoke, PermApprove, PermManage},
+	RoleWriter:       {PermList, PermRead, PermCreate, PermUpdate, PermDelete, PermRevoke},
+	RoleReader:       {PermList, PermRead},
+	RoleMetadataOnly: {PermList},
+}
+
+// capabilities a policy rule can grant; managing and owning projects stays with roles
+var policyCapabilities = []string{string(PermRead), string(PermCreate), string(PermUpdate), string(PermDelete), string(PermRevoke), string(PermApprove), string(PermList), CapDeny, CapAutoGrant}
+
+// ValidRole reports whether role is one of the project roles
+func ValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// ValidPermission reports whether perm is a known permission
+func ValidPermission(perm Permission) bool {
+	return RoleAllows(RoleOwner, perm)
+}
+
+// RoleAllows reports whether role grants perm
+func RoleAllows(role string, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// ResourcePath is the path policies match against: "<projectId>" for the
+// project itself and "<projectId>/<secretName>" for its secrets
+func ResourcePath(projectID string, secretName string) string {
+	if secretName == "" {
+		return projectID
+	}
+	return projectID + "/" + secretName
+}
+
+// Subject is who an authorization decision is made for
+type Subject struct {
+	UserID    string   `json:"user_id"`
+	Groups    []string `json:"groups,omitempty"`
+	Orgs      []string `json:"orgs,omitempty"`
+	OrgAdmins []string `json:"org_admins,omitempty"`
+	Teams     []string `json:"teams,omitempty"`
+
+	Certificate string `json:"certificate,omitempty"` // SAN or CN of a client certificate
+
+	// projects a JWT is limited to by its project_ids claim; nil is any project
+	ProjectIDs []string `json:"project_ids,omitempty"`
+
+	ServiceAccount *identity.ServiceAccount `json:"service_account,omitempty"`
+}
+
+// MatchedRule is a policy rule that applied to a decision
+type MatchedRule struct {
+	Policy       string   `json:"policy"`
+	Principal    string   `json:"principal"` // e.g. "group:platform"
+	Path         string   `json:"path"`
+	Capabilities []string `json:"capabilities"`
+}
+
+// Decision is the outcome of an authorization check and why
+type Decision struct {
+	Allowed    bool          `json:"allowed"`
+	Path       string        `json:"path"`
+	Permission Permission    `json:"permission"`
+	Role       string        `json:"role,omitempty"`
+	Reason     string        `json:"reason"`
+	Rules      []MatchedRule `json:"matched_rules,omitempty"`
+	GrantID    *uuid.UUID    `json:"grant_id,omitempty"` // just-in-time grant that allowed it
+	BreakGlass *uuid.UUID    `json:"break_glass_session_id,omitempty"`
+}
+
+// Authorizer decides what a user may do on a project or secret. Every access
+// check goes through Decide.
+type Authorizer struct {
+	projectRepo    *repository.ProjectRepository
+	memberRepo     *repository.MemberRepository
+	policyRepo     *repository.PolicyRepository
+	orgRepo        *repository.OrgRepository
+	grantRepo      *repository.AccessGrantRepository
+	breakGlassRepo *repository.BreakGlassRepository
+}
+
+func NewAuthorizer(projectRepo *repository.ProjectRepository, memberRepo *repository.MemberRepository, policyRepo *repository.PolicyRepository, orgRepo *repository.OrgRepository, grantRepo *repository.AccessGrantRepository, breakGlassRepo *repository.BreakGlassRepository) *Authorizer {
+	return &Authorizer{
+		projectRepo:    projectRepo,
+		memberRepo:     memberRepo,
+		policyRepo:     policyRepo,
+		orgRepo:        orgRepo,
+		grantRepo:      grantRepo,
+		breakGlassRepo: breakGlassRepo,
+	}
+}
+
+// Project loads a live project without any access check
+func (a *Authorizer) Project(ctx context.Context, projectID string) (*models.Project, error) {
+	project, err := a.projectRepo.GetProjectByID(ctx, projectID)
+	if err != nil || project == nil || project.DeletedAt != nil {
+		return nil, errors.New("project not found")
+	}
+	return project, nil
+}
+
+// Authorize loads a live project and makes sure the user may perform perm on it
+func (a *Authorizer) Authorize(ctx context.Context, userID string, projectID string, perm Permission) (*models.Project, error) {
+	project, err := a.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.Check(ctx, project, userID, perm); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// AuthorizeSecret loads a live secret of a live project and makes sure the
+// user may perform perm on it
+func (a *Authorizer) AuthorizeSecret(ctx context.Context, secretRepo *repository.SecretRepository, userID string, projectID string, secretID string, perm Permission) (*models.Project, *models.Secret, error) {
+	project, secret, _, err := a.authorizeSecret(ctx, secretRepo, userID, projectID, secretID, perm)
+	return project, secret, err
+}
+
+// authorizeSecret is AuthorizeSecret that also returns the decision, for
+// callers that audit the grant behind it
+func (a *Authorizer) authorizeSecret(ctx context.Context, secretRepo *repository.SecretRepository, userID string, projectID string, secretID string, perm Permission) (*models.Project, *models.Secret, *Decision, error) {
+	project, err := a.Project(ctx, projectID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	secret, err := projectSecret(ctx, secretRepo, project, secretID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	decision, err := a.checkSecret(ctx, project, userID, secret.Name, perm)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return project, secret, decision, nil
+}
+
+// Check makes sure the user may perform perm on an already loaded project,
+// which may also be one in the trash
+func (a *Authorizer) Check(ctx context.Context, project *models.Project, userID string, perm Permission) error {
+	return a.CheckSecret(ctx, project, userID, "", perm)
+}
+
+// CheckSecret makes sure the user may perform perm on the named secret of a
+// project; an empty name checks the project itself
+func (a *Authorizer) CheckSecret(ctx context.Context, project *models.Project, userID string, secretName string, perm Permission) error {
+	_, err := a.checkSecret(ctx, project, userID, secretName, perm)
+	return err
+}
+
+func (a *Authorizer) checkSecret(ctx context.Context, project *models.Project, userID string, secretName string, perm Permission) (*Decision, error) {
+	decision, err := a.Decide(ctx, project, subjectFor(ctx, userID), secretName, perm)
+	if err != nil {
+		return nil, err
+	}
+	if decision.Allowed {
+		if decision.BreakGlass != nil {
+			identity.FromContext(ctx).MarkBreakGlass(project.ID.String(), *decision.BreakGlass)
+		}
+		return decision, nil
+	}
+	sa := subjectFor(ctx, userID).ServiceAccount
+	if decision.Role == "" && len(decision.Rules) == 0 && !machineReaches(sa, project.ID.String()) {
+		// nothing relates the user to this project, do not explain more
+		return nil, errors.New("unauthorized")
+	}
+	return nil, errors.New("forbidden: " + decision.Reason)
+}
+
+// Decide makes the authorization decision. Project roles grant permissions on
+// the whole project, attached policies grant them on path globs, a
+// break-glass session gives an administrator admin rights for a while, active
+// just-in-time grants give temporary read access, and a matching deny rule
+// overrides all of them.
+func (a *Authorizer) Decide(ctx context.Context, project *models.Project, subject Subject, secretName string, perm Permission) (*Decision, error) {
+	decision := &Decision{
+		Path:       ResourcePath(project.ID.String(), secretName),
+		Permission: perm,
+	}
+
+	// service accounts are never project members
+	var role string
+	if subject.ServiceAccount == nil {
+		var err error
+		role, err = a.role(ctx, project, subject)
+		if err != nil {
+			return nil, err
+		}
+		decision.Role = role
+	}
+
+	rules, err := a.matchingRules(ctx, subject, decision.Path)
+	if err != nil {
+		return nil, err
+	}
+	decision.Rules = rules
+
+	for _, rule := range rules {
+		if containsString(rule.Capabilities, CapDeny) {
+			decision.Reason = "denied by policy " + rule.Policy + " on " + rule.Path
+			return decision, nil
+		}
+	}
+
+	if subject.ProjectIDs != nil && !containsString(subject.ProjectIDs, project.ID.String()) {
+		decision.Reason = "token is limited to projects " + strings.Join(subject.ProjectIDs, ", ")
+		return decision, nil
+	}
+
+	if subject.ServiceAccount != nil {
+		decideServiceAccount(decision, subject.ServiceAccount, project, secretName, rules)
+		return decision, nil
+	}
+
+	if role != "" && RoleAllows(role, perm) {
+		decision.Allowed = true
+		decision.Reason = "role " + role + " grants " + string(perm)
+		return decision, nil
+	}
+
+	for _, rule := range rules {
+		if containsString(rule.Capabilities, string(perm)) {
+			decision.Allowed = true
+			decision.Reason = "policy " + rule.Policy + " grants " + string(perm) + " on " + rule.Path + " to " + rule.Principal
+			return decision, nil
+		}
+	}
+
+	session, err := a.breakGlassRepo.GetActiveSession(ctx, subject.UserID, project.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if session != nil && RoleAllows(RoleAdmin, perm) {
+		decision.Allowed = true
+		decision.BreakGlass = &session.ID
+		decision.Reason = "break-glass session " + session.ID.String() + " gives admin rights until " + session.ExpiresAt.Format(time.RFC3339)
+		return decision, nil
+	}
+
+	if containsString(grantPermissions, string(perm)) {
+		grants, err := a.grantRepo.GetActiveGrants(ctx, subject.UserID, project.ID.String())
+		if err != nil {
+			return nil, err
+		}
+		for _, grant := range grants {
+			if grantCovers(grant, secretName) {
+				decision.Allowed = true
+				decision.GrantID = &grant.ID
+				decision.Reason = "access grant " + grant.ID.String() + " gives " + string(perm) + " on " + grant.Path + " until " + grant.ExpiresAt.Format(time.RFC3339)
+				return decision, nil
+			}
+		}
+	}
+
+	if role == "" {
+		decision.Reason = "no project role and no policy grants " + string(perm) + " on " + decision.Path
+	} else {
+		decision.Reason = "role " + role + " does not grant " + string(perm) + " and no policy does"
+	}
+	return decision, nil
+}
+
+// decideServiceAccount allows a machine token what its scopes allow within its
+// project and path prefix (or AppRole paths), or what an attached policy grants. The token
+// scopes bound everything, whatever the policies say.
+func decideServiceAccount(decision *Decision, sa *identity.ServiceAccount, project *models.Project, secretName string, rules []MatchedRule) {
+	perm := string(decision.Permission)
+	if !containsString(sa.Scopes, perm) {
+		decision.Reason = "token scopes do not include " + perm
+		return
+	}
+
+	scope := ResourcePath(sa.ProjectID, sa.PathPrefix) + "*"
+	if sa.ProjectID == project.ID.String() && (sa.PathPrefix == "" || (secretName != "" && strings.HasPrefix(secretName, sa.PathPrefix))) {
+		decision.Allowed = true
+		decision.Reason = "service account " + sa.ID + " is scoped to " + scope
+		return
+	}
+	if sa.ProjectID == "" {
+		scope = strings.Join(sa.Paths, ", ")
+	}
+	for _, path := range sa.Paths {
+		if utils.MatchPathGlob(path, decision.Path) {
+			decision.Allowed = true
+			decision.Reason = "approle " + sa.ID + " is limited to " + path
+			return
+		}
+	}
+
+	for _, rule := range rules {
+		if containsString(rule.Capabilities, perm) {
+			decision.Allowed = true
+			decision.Reason = "policy " + rule.Policy + " grants " + perm + " on " + rule.Path + " to " + rule.Principal
+			return
+		}
+	}
+	decision.Reason = "machine identity " + sa.ID + " is limited to " + scope
+}
+
+// machineReaches reports whether a machine identity is bound to the project at all
+func machineReaches(sa *identity.ServiceAccount, projectID string) bool {
+	if sa == nil {
+		return false
+	}
+	if sa.ProjectID == projectID {
+		return true
+	}
+	for _, path := range sa.Paths {
+		if path == projectID || strings.HasPrefix(path, projectID+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Role returns the role of the user on the project, or "" for non-members
+func (a *Authorizer) Role(ctx context.Context, project *models.Project, userID string) (string, error) {
+	return a.role(ctx, project, subjectFor(ctx, userID))
+}
+
+func (a *Authorizer) role(ctx context.Context, project *models.Project, subject Subject) (string, error) {
+	owner, err := a.isOwner(ctx, project, subject)
+	if err != nil {
+		return "", err
+	}
+	if owner {
+		return RoleOwner, nil
+	}
+	member, err := a.memberRepo.GetMember(ctx, project.ID.String(), subject.UserID)
+	if err != nil {
+		return "", err
+	}
+	if member == nil {
+		return "", nil
+	}
+	return member.Role, nil
+}
+
+// isOwner reports whether the subject owns the project: as its user owner, as
+// a member of its owning team, or as an admin of the org it belongs to
+func (a *Authorizer) isOwner(ctx context.Context, project *models.Project, subject Subject) (bool, error) {
+	switch project.OwnerType {
+	case OwnerTeam:
+		if project.OwnerID == nil {
+			break
+		}
+		path, err := a.teamPath(ctx, project.OwnerID.String())
+		if err != nil {
+			return false, err
+		}
+		if path != "" && containsString(subject.Teams, path) {
+			return true, nil
+		}
+	case OwnerOrg:
+		// org projects are owned by the org admins, checked below
+	default:
+		if project.UserID.String() == subject.UserID {
+			return true, nil
+		}
+	}
+
+	if project.OrgID == nil || len(subject.OrgAdmins) == 0 {
+		return false, nil
+	}
+	org, err := a.orgRepo.GetOrgByID(ctx, project.OrgID.String())
+	if err != nil || org == nil {
+		return false, err
+	}
+	return containsString(subject.OrgAdmins, org.Slug), nil
+}
+
+// OwnerPrincipals lists who owns the project, e.g. "user:<id>", "team:acme/sre"
+// or "org:acme" for the org admins, including members with the owner role
+func (a *Authorizer) OwnerPrincipals(ctx context.Context, project *models.Project) ([]string, error) {
+	var owners []string
+	switch project.OwnerType {
+	case OwnerTeam:
+		if project.OwnerID != nil {
+			path, err := a.teamPath(ctx, project.OwnerID.String())
+			if err != nil {
+				return nil, err
+			}
+			if path != "" {
+				owners = append(owners, "team:"+path)
+			}
+		}
+	case OwnerOrg:
+	default:
+		owners = append(owners, PrincipalUser+":"+project.UserID.String())
+	}
+	if project.OrgID != nil {
+		org, err := a.orgRepo.GetOrgByID(ctx, project.OrgID.String())
+		if err != nil {
+			return nil, err
+		}
+		if org != nil {
+			owners = append(owners, "org:"+org.Slug)
+		}
+	}
+
+	members, err := a.memberRepo.GetMembersByProject(ctx, project.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		if member.Role == RoleOwner {
+			owners = append(owners, PrincipalUser+":"+member.UserID.String())
+		}
+	}
+	return owners, nil
+}
+
+// teamPath returns the "<orgSlug>/<teamSlug>" the gateway uses for a team, or "" if it is gone
+func (a *Authorizer) teamPath(ctx context.Context, teamID string) (string, error) {
+	team, err := a.orgRepo.GetTeamByID(ctx, teamID)
+	if err != nil || team == nil {
+		return "", err
+	}
+	org, err := a.orgRepo.GetOrgByID(ctx, team.OrgID.String())
+	if err != nil || org == nil {
+		return "", err
+	}
+	return org.Slug + "/" + team.Slug, nil
+}
+
+// Ownership resolves the teams and admin orgs of the request identity to IDs,
+// for listing the projects a user owns through them
+func (a *Authorizer) Ownership(ctx context.Context, userID string) (repository.ProjectOwnership, error) {
+	subject := subjectFor(ctx, userID)
+	ownership := repository.ProjectOwnership{UserID: userID}
+
+	teams, err := a.orgRepo.GetTeamsByPaths(ctx, subject.Teams)
+	if err != nil {
+		return ownership, err
+	}
+	for _, team := range teams {
+		ownership.TeamIDs = append(ownership.TeamIDs, team.ID.String())
+	}
+
+	orgs, err := a.orgRepo.GetOrgsBySlugs(ctx, subject.OrgAdmins)
+	if err != nil {
+		return ownership, err
+	}
+	for _, org := range orgs {
+		ownership.AdminOrgIDs = append(ownership.AdminOrgIDs, org.ID.String())
+	}
+	return ownership, nil
+}
+
+// TokenProjects drops the projects the user's token is not limited to, if it is
+func TokenProjects(ctx context.Context, userID string, projects []models.Project) []models.Project {
+	limit := subjectFor(ctx, userID).ProjectIDs
+	if limit == nil {
+		return projects
+	}
+	allowed := make([]models.Project, 0, len(projects))
+	for _, project := range projects {
+		if containsString(limit, project.ID.String()) {
+			allowed = append(allowed, project)
+		}
+	}
+	return allowed
+}
+
+// PlaceProject checks that the user may hand a project to the given owner and
+// fills in its owner fields. Projects can go to any user, to a team the user
+// is in (or whose org they administer), or to an org the user belongs to.
+func (a *Authorizer) PlaceProject(ctx context.Context, userID string, project *models.Project, ownerType string, ownerID string) error {
+	subject := subjectFor(ctx, userID)
+
+	switch ownerType {
+	case OwnerUser:
+		ownerUUID, err := uuid.Parse(ownerID)
+		if err != nil {
+			return errors.New("owner_id must be a user id")
+		}
+		project.UserID = ownerUUID
+		project.OwnerType = OwnerUser
+		project.OwnerID = nil
+		project.OrgID = nil
+
+	case OwnerTeam:
+		if _, err := uuid.Parse(ownerID); err != nil {
+			return errors.New("team not found")
+		}
+		team, err := a.orgRepo.GetTeamByID(ctx, ownerID)
+		if err != nil || team == nil {
+			return errors.New("team not found")
+		}
+		org, err := a.orgRepo.GetOrgByID(ctx, team.OrgID.String())
+		if err != nil || org == nil {
+			return errors.New("team not found")
+		}
+		if !containsString(subject.Teams, org.Slug+"/"+team.Slug) && !containsString(subject.OrgAdmins, org.Slug) {
+			return errors.New("forbidden: you are not a member of team " + org.Slug + "/" + team.Slug)
+		}
+		project.OwnerType = OwnerTeam
+		project.OwnerID = &team.ID
+		project.OrgID = &org.ID
+
+	case OwnerOrg:
+		if _, err := uuid.Parse(ownerID); err != nil {
+			return errors.New("organization not found")
+		}
+		org, err := a.orgRepo.GetOrgByID(ctx, ownerID)
+		if err != nil || org == nil {
+			return errors.New("organization not found")
+		}
+		if !containsString(subject.Orgs, org.Slug) && !containsString(subject.OrgAdmins, org.Slug) {
+			return errors.New("forbidden: you are not a member of organization " + org.Slug)
+		}
+		project.OwnerType = OwnerOrg
+		project.OwnerID = &org.ID
+		project.OrgID = &org.ID
+
+	default:
+		return errors.New("owner_type must be user, team or org")
+	}
+	return nil
+}
+
+// OwnerUserID returns the user owning a project, or "" when a team or org owns it
+func OwnerUserID(project *models.Project) string {
+	if project.OwnerType != "" && project.OwnerType != OwnerUser {
+		return ""
+	}
+	return project.UserID.String()
+}
+
+// matchingRules returns the rules of every policy attached to the subject
+// whose path glob matches path
+func (a *Authorizer) matchingRules(ctx context.Context, subject Subject, path string) ([]MatchedRule, error) {
+	rules, err := a.subjectRules(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+	var matched []MatchedRule
+	for _, rule := range rules {
+		if utils.MatchPathGlob(rule.Path, path) {
+			matched = append(matched, rule)
+		}
+	}
+	return matched, nil
+}
+
+// subjectRules returns the rules of every policy attached to the subject
+func (a *Authorizer) subjectRules(ctx context.Context, subject Subject) ([]MatchedRule, error) {
+	principals := []repository.Principal{{Type: PrincipalUser, ID: subject.UserID}}
+	if subject.ServiceAccount != nil {
+		principals = []repository.Principal{{Type: PrincipalServiceAccount, ID: subject.ServiceAccount.ID}}
+	}
+	for _, group := range subject.Groups {
+		principals = append(principals, repository.Principal{Type: PrincipalGroup, ID: group})
+	}
+	if subject.Certificate != "" {
+		principals = append(principals, repository.Principal{Type: PrincipalCertificate, ID: subject.Certificate})
+	}
+
+	attachments, err := a.policyRepo.GetAttachmentsForPrincipals(ctx, principals)
+	if err != nil || len(attachments) == 0 {
+		return nil, err
+	}
+
+	// a policy reachable through several principals is reported once
+	principalOf := map[string]string{}
+	var policyIDs []string
+	for _, attachment := range attachments {
+		id := attachment.PolicyID.String()
+		if _, seen := principalOf[id]; seen {
+			continue
+		}
+		principalOf[id] = attachment.PrincipalType + ":" + attachment.PrincipalID
+		policyIDs = append(policyIDs, id)
+	}
+
+	policies, err := a.policyRepo.GetPoliciesByIDs(ctx, policyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []MatchedRule
+	for _, policy := range policies {
+		for _, rule := range policy.Rules {
+			rules = append(rules, MatchedRule{
+				Policy:       policy.Name,
+				Principal:    principalOf[policy.ID.String()],
+				Path:         rule.Path,
+				Capabilities: rule.Capabilities,
+			})
+		}
+	}
+	return rules, nil
+}
+
+// subjectFor builds the subject of a check, taking the groups, orgs and teams
+// from the identity of the request when it is the same user
+func subjectFor(ctx context.Context, userID string) Subject {
+	subject := Subject{UserID: userID}
+	if id := identity.FromContext(ctx); id != nil && id.UserID == userID {
+		subject.Groups = id.Groups
+		subject.Orgs = id.Orgs
+		subject.OrgAdmins = id.OrgAdmins
+		subject.Teams = id.Teams
+		subject.ProjectIDs = id.ProjectIDs
+		subject.Certificate = id.Certificate
+		subject.ServiceAccount = id.ServiceAccount
+	}
+	return subject
+}
+
+// projectSecret loads a live secret and makes sure it belongs to the project
+func projectSecret(ctx context.Context, repo *repository.SecretRepository, project *models.Project, secretID string) (*models.Secret, error) {
+	secret, err := repo.GetSecretByID(ctx, secretID)
+	if err != nil || secret == nil || secret.DeletedAt != nil || secret.ProjectID != project.ID {
+		return nil, errors.New("secret not found")
+	}
+	return secret, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
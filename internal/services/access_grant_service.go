@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const maxGrantDuration = 24 * time.Hour
+
+const (
+	GrantPending  = "pending"
+	GrantActive   = "active"
+	GrantRejected = "rejected"
+	GrantRevoked  = "revoked"
+	GrantExpired  = "expired"
+)
+
+var grantStatuses = []string{GrantPending, GrantActive, GrantRejected, GrantRevoked, GrantExpired}
+
+// a just-in-time grant only gives read access
+var grantPermissions = []string{string(PermRead), string(PermList)}
+
+var errGrantNotFound = errors.New("access grant not found")
+
+type AccessGrantService struct {
+	repo         *repository.AccessGrantRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+}
+
+func NewAccessGrantService(repo *repository.AccessGrantRepository, authorizer *Authorizer, auditService *AuditService) *AccessGrantService {
+	return &AccessGrantService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+	}
+}
+
+// AccessGrantInput describes requested access. An empty Path asks for the
+// whole project.
+type AccessGrantInput struct {
+	Path          string // secret name glob within the project
+	Duration      *time.Duration
+	Justification string
+}
+
+// RequestGrant asks for temporary read access to a project or the secrets
+// matching a glob. It waits for an approver unless an auto-grant policy rule
+// covers the path, in which case it is active right away.
+func (s *AccessGrantService) RequestGrant(ctx context.Context, userID string, projectID string, input AccessGrantInput) (*models.AccessGrant, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if subjectFor(ctx, userID).ServiceAccount != nil {
+		return nil, errors.New("forbidden: machine identities cannot request access grants")
+	}
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	justification := strings.TrimSpace(input.Justification)
+	if justification == "" {
+		return nil, errors.New("justification is required")
+	}
+	if input.Duration == nil {
+		return nil, errors.New("duration is required")
+	}
+	if *input.Duration < time.Minute || *input.Duration > maxGrantDuration {
+		return nil, errors.New("duration must be between 1m and " + maxGrantDuration.String())
+	}
+	path := input.Path
+	if path == "" {
+		path = "**"
+	}
+	if err := utils.ValidatePathGlob(path); err != nil {
+		return nil, errors.New("path " + path + ": " + err.Error())
+	}
+
+	now := time.Now()
+	grant := &models.AccessGrant{
+		ID:            uuid.New(),
+		ProjectID:     project.ID,
+		UserID:        userUUID,
+		Path:          path,
+		Justification: justification,
+		Duration:      int64(*input.Duration / time.Second),
+		Status:        GrantPending,
+		CreatedAt:     now,
+	}
+
+	rule, err := s.autoGrantRule(ctx, project, userID, path)
+	if err != nil {
+		return nil, err
+	}
+	if rule != nil {
+		expiresAt := now.Add(*input.Duration)
+		grant.Status = GrantActive
+		grant.AutoApproved = true
+		grant.DecidedAt = &now
+		grant.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.CreateGrant(ctx, grant); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.LogGrant(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		&grant.ID,
+		"ACCESS_GRANT_REQUESTED",
+		"Read access to "+path+" requested for "+input.Duration.String()+": "+justification,
+	)
+	if rule != nil {
+		s.AuditService.LogGrant(
+			ctx,
+			&userUUID,
+			&project.ID,
+			nil,
+			&grant.ID,
+			"ACCESS_GRANT_APPROVED",
+			"Access grant auto-approved by policy "+rule.Policy+" on "+rule.Path+" until "+grant.ExpiresAt.Format(time.RFC3339),
+		)
+	}
+	return grant, nil
+}
+
+// ListGrants lists the grants of a project. Approvers see everyone's, other
+// users only their own.
+func (s *AccessGrantService) ListGrants(ctx context.Context, userID string, projectID string, status string) ([]models.AccessGrant, error) {
+	if status != "" && !containsString(grantStatuses, status) {
+		return nil, errors.New("unknown status " + status)
+	}
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := userID
+	if s.Authorizer.Check(ctx, project, userID, PermApprove) == nil {
+		owner = ""
+	}
+	return s.repo.GetGrantsByProject(ctx, projectID, owner, status)
+}
+
+// ApproveGrant activates a pending grant of another user for its duration
+func (s *AccessGrantService) ApproveGrant(ctx context.Context, userID string, projectID string, grantID string) (*models.AccessGrant, error) {
+	userUUID := uuid.MustParse(userID)
+
+	grant, err := s.pendingGrant(ctx, userID, projectID, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant.UserID == userUUID {
+		return nil, errors.New("forbidden: an access grant must be approved by someone other than its requester")
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(grant.Duration) * time.Second)
+	grant.Status = GrantActive
+	grant.DecidedBy = &userUUID
+	grant.DecidedAt = &now
+	grant.ExpiresAt = &expiresAt
+	ok, err := s.repo.Transition(ctx, grant, GrantPending)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("access grant is no longer pending")
+	}
+
+	s.AuditService.LogGrant(
+		ctx,
+		&userUUID,
+		&grant.ProjectID,
+		nil,
+		&grant.ID,
+		"ACCESS_GRANT_APPROVED",
+		"Read access to "+grant.Path+" for user "+grant.UserID.String()+" approved until "+expiresAt.Format(time.RFC3339),
+	)
+	return grant, nil
+}
+
+func (s *AccessGrantService) RejectGrant(ctx context.Context, userID string, projectID string, grantID string) (*models.AccessGrant, error) {
+	userUUID := uuid.MustParse(userID)
+
+	grant, err := s.pendingGrant(ctx, userID, projectID, grantID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	grant.Status = GrantRejected
+	grant.DecidedBy = &userUUID
+	grant.DecidedAt = &now
+	ok, err := s.repo.Transition(ctx, grant, GrantPending)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("access grant is no longer pending")
+	}
+
+	s.AuditService.LogGrant(
+		ctx,
+		&userUUID,
+		&grant.ProjectID,
+		nil,
+		&grant.ID,
+		"ACCESS_GRANT_REJECTED",
+		"Read access to "+grant.Path+" for user "+grant.UserID.String()+" rejected",
+	)
+	return grant, nil
+}
+
+// RevokeGrant ends a pending or active grant early. The grantee can give up
+// their own grant, approvers can revoke anyone's.
+func (s *AccessGrantService) RevokeGrant(ctx context.Context, userID string, projectID string, grantID string) (*models.AccessGrant, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	grant, err := s.grant(ctx, project, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant.UserID != userUUID {
+		if err := s.Authorizer.Check(ctx, project, userID, PermApprove); err != nil {
+			return nil, errGrantNotFound
+		}
+	}
+	if grant.Status != GrantPending && grant.Status != GrantActive {
+		return nil, errors.New("access grant is already " + grant.Status)
+	}
+
+	from := grant.Status
+	now := time.Now()
+	grant.Status = GrantRevoked
+	grant.RevokedBy = &userUUID
+	grant.RevokedAt = &now
+	ok, err := s.repo.Transition(ctx, grant, from)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("access grant changed meanwhile, try again")
+	}
+
+	s.AuditService.LogGrant(
+		ctx,
+		&userUUID,
+		&grant.ProjectID,
+		nil,
+		&grant.ID,
+		"ACCESS_GRANT_REVOKED",
+		"Access grant of user "+grant.UserID.String()+" to "+grant.Path+" revoked",
+	)
+	return grant, nil
+}
+
+// ExpireDue marks the grants that ran out as expired; the authorizer already
+// ignores them, this records their end in the audit log
+func (s *AccessGrantService) ExpireDue(ctx context.Context) (int, error) {
+	grants, err := s.repo.ExpireGrants(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, grant := range grants {
+		s.AuditService.LogGrant(
+			ctx,
+			&grant.UserID,
+			&grant.ProjectID,
+			nil,
+			&grant.ID,
+			"ACCESS_GRANT_EXPIRED",
+			"Read access to "+grant.Path+" expired",
+		)
+	}
+	return len(grants), nil
+}
+
+// pendingGrant loads a pending grant of a project where the user may approve
+func (s *AccessGrantService) pendingGrant(ctx context.Context, userID string, projectID string, grantID string) (*models.AccessGrant, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermApprove)
+	if err != nil {
+		return nil, err
+	}
+	grant, err := s.grant(ctx, project, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant.Status != GrantPending {
+		return nil, errors.New("access grant is " + grant.Status + ", not pending")
+	}
+	return grant, nil
+}
+
+func (s *AccessGrantService) grant(ctx context.Context, project *models.Project, grantID string) (*models.AccessGrant, error) {
+	if _, err := uuid.Parse(grantID); err != nil {
+		return nil, errGrantNotFound
+	}
+	grant, err := s.repo.GetGrantByID(ctx, grantID)
+	if err != nil {
+		return nil, err
+	}
+	if grant == nil || grant.ProjectID != project.ID {
+		return nil, errGrantNotFound
+	}
+	return grant, nil
+}
+
+// autoGrantRule returns the policy rule that approves a grant on path for
+// the user without an approver, or nil. The path is a glob, so the rule must
+// cover every secret it can match, and a deny rule on any of them prevents
+// it. Requests that cannot be shown to fit go to an approver.
+func (s *AccessGrantService) autoGrantRule(ctx context.Context, project *models.Project, userID string, path string) (*MatchedRule, error) {
+	rules, err := s.Authorizer.subjectRules(ctx, subjectFor(ctx, userID))
+	if err != nil {
+		return nil, err
+	}
+	requested := ResourcePath(project.ID.String(), path)
+	var auto *MatchedRule
+	for i, rule := range rules {
+		if containsString(rule.Capabilities, CapDeny) && utils.GlobsOverlap(rule.Path, requested) {
+			return nil, nil
+		}
+		if auto == nil && containsString(rule.Capabilities, CapAutoGrant) && utils.GlobCovers(rule.Path, requested) {
+			auto = &rules[i]
+		}
+	}
+	return auto, nil
+}
+
+// grantCovers reports whether a grant reaches the named secret, or the
+// project itself for an empty name
+func grantCovers(grant models.AccessGrant, secretName string) bool {
+	if grant.Path == "**" {
+		return true
+	}
+	return secretName != "" && utils.MatchPathGlob(grant.Path, secretName)
+}
@@ -0,0 +1,365 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultAppRoleTokenTTL = 15 * time.Minute
+	maxAppRoleTokenTTL     = time.Hour
+)
+
+type AppRoleService struct {
+	repo         *repository.AppRoleRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+}
+
+func NewAppRoleService(repo *repository.AppRoleRepository, authorizer *Authorizer, auditService *AuditService) *AppRoleService {
+	return &AppRoleService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+	}
+}
+
+// AppRoleInput describes a new role. Nil durations and uses fall back to the
+// defaults: 15 minute tokens and secret IDs without expiry or use limit.
+type AppRoleInput struct {
+	Name            string
+	Paths           []string
+	Scopes          []string
+	TokenTTL        *time.Duration
+	SecretIDTTL     *time.Duration
+	SecretIDNumUses *int
+}
+
+// CreateRole creates an AppRole. Every path must start with the ID of a
+// project the user manages.
+func (s *AppRoleService) CreateRole(ctx context.Context, userID string, input AppRoleInput) (*models.AppRole, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if strings.TrimSpace(input.Name) == "" {
+		return nil, errors.New("name is required")
+	}
+	if len(input.Paths) == 0 {
+		return nil, errors.New("a role needs at least one path")
+	}
+	for _, path := range input.Paths {
+		if err := utils.ValidatePathGlob(path); err != nil {
+			return nil, errors.New("path " + path + ": " + err.Error())
+		}
+	}
+	scopes := input.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{string(PermRead), string(PermList)}
+	}
+	for _, scope := range scopes {
+		if !containsString(serviceTokenScopes, scope) {
+			return nil, errors.New("unknown scope " + scope)
+		}
+	}
+
+	tokenTTL := defaultAppRoleTokenTTL
+	if input.TokenTTL != nil {
+		if *input.TokenTTL <= 0 || *input.TokenTTL > maxAppRoleTokenTTL {
+			return nil, errors.New("token_ttl must be between 1s and " + maxAppRoleTokenTTL.String())
+		}
+		tokenTTL = *input.TokenTTL
+	}
+
+	role := &models.AppRole{
+		Name:            input.Name,
+		Paths:           input.Paths,
+		Scopes:          scopes,
+		TokenTTL:        int64(tokenTTL / time.Second),
+		SecretIDNumUses: input.SecretIDNumUses,
+		CreatedBy:       userUUID,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+	if input.SecretIDTTL != nil {
+		if *input.SecretIDTTL <= 0 {
+			return nil, errors.New("secret_id_ttl must be positive")
+		}
+		seconds := int64(*input.SecretIDTTL / time.Second)
+		role.SecretIDTTL = &seconds
+	}
+	if input.SecretIDNumUses != nil && *input.SecretIDNumUses < 1 {
+		return nil, errors.New("secret_id_num_uses must be at least 1")
+	}
+
+	if err := s.checkManage(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateRole(ctx, role); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("an approle named " + input.Name + " already exists")
+		}
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"CREATE_APPROLE",
+		"AppRole "+role.Name+" created for "+strings.Join(role.Paths, ", ")+" with scopes "+strings.Join(role.Scopes, ","),
+	)
+	return role, nil
+}
+
+// ListRoles lists the roles the user can manage
+func (s *AppRoleService) ListRoles(ctx context.Context, userID string) ([]models.AppRole, error) {
+	roles, err := s.repo.GetRoles(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manageable := make([]models.AppRole, 0, len(roles))
+	for _, role := range roles {
+		if s.checkManage(ctx, userID, &role) == nil {
+			manageable = append(manageable, role)
+		}
+	}
+	return manageable, nil
+}
+
+func (s *AppRoleService) GetRole(ctx context.Context, userID string, roleID string) (*models.AppRole, error) {
+	return s.role(ctx, userID, roleID)
+}
+
+// DeleteRole deletes a role and its secret IDs. Tokens already issued stop
+// working, auth checks that their role still exists.
+func (s *AppRoleService) DeleteRole(ctx context.Context, userID string, roleID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	role, err := s.role(ctx, userID, roleID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteRole(ctx, roleID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"DELETE_APPROLE",
+		"AppRole "+role.Name+" deleted with its secret IDs",
+	)
+	return nil
+}
+
+// GenerateSecretID issues a secret ID for the role. It is returned once; only
+// its hash is stored.
+func (s *AppRoleService) GenerateSecretID(ctx context.Context, userID string, roleID string) (*models.AppRoleSecretID, string, error) {
+	userUUID := uuid.MustParse(userID)
+
+	role, err := s.role(ctx, userID, roleID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, err := utils.RandomToken("", 32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secretID := &models.AppRoleSecretID{
+		RoleID:        role.ID,
+		SecretHash:    utils.HashToken(plaintext),
+		UsesRemaining: role.SecretIDNumUses,
+		CreatedBy:     userUUID,
+		CreatedAt:     time.Now(),
+	}
+	if role.SecretIDTTL != nil {
+		expiresAt := time.Now().Add(time.Duration(*role.SecretIDTTL) * time.Second)
+		secretID.ExpiresAt = &expiresAt
+	}
+	if err := s.repo.CreateSecretID(ctx, secretID); err != nil {
+		return nil, "", err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"GENERATE_APPROLE_SECRET_ID",
+		"Secret ID "+secretID.Accessor.String()+" issued for AppRole "+role.Name,
+	)
+	return secretID, plaintext, nil
+}
+
+func (s *AppRoleService) ListSecretIDs(ctx context.Context, userID string, roleID string) ([]models.AppRoleSecretID, error) {
+	role, err := s.role(ctx, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetSecretIDsByRole(ctx, role.ID.String())
+}
+
+func (s *AppRoleService) DestroySecretID(ctx context.Context, userID string, roleID string, accessor string) error {
+	userUUID := uuid.MustParse(userID)
+
+	role, err := s.role(ctx, userID, roleID)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(accessor); err != nil {
+		return errors.New("secret id not found")
+	}
+	deleted, err := s.repo.DeleteSecretID(ctx, roleID, accessor)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return errors.New("secret id not found")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"DESTROY_APPROLE_SECRET_ID",
+		"Secret ID "+accessor+" of AppRole "+role.Name+" destroyed",
+	)
+	return nil
+}
+
+// Login exchanges a role ID and secret ID for a signed token limited to the
+// role's paths and scopes
+func (s *AppRoleService) Login(ctx context.Context, roleID string, secretID string, ip string) (string, time.Time, error) {
+	invalid := errors.New("invalid role_id or secret_id")
+
+	roleUUID, err := uuid.Parse(roleID)
+	if err != nil || secretID == "" {
+		return "", time.Time{}, invalid
+	}
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if role == nil {
+		return "", time.Time{}, invalid
+	}
+
+	used, err := s.repo.ConsumeSecretID(ctx, roleID, utils.HashToken(secretID))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if used == nil {
+		s.AuditService.Log(
+			ctx,
+			&roleUUID,
+			nil,
+			nil,
+			"APPROLE_LOGIN_FAILED",
+			"AppRole "+role.Name+" login from "+ip+" with an unknown, expired or used up secret ID",
+		)
+		return "", time.Time{}, invalid
+	}
+
+	key, err := utils.AppRoleSigningKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(role.TokenTTL) * time.Second)
+	token, err := utils.SignAppRoleToken(key, &utils.AppRoleClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   role.ID.String(),
+			ID:        uuid.NewString(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		RoleName: role.Name,
+		Paths:    role.Paths,
+		Scopes:   role.Scopes,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	remaining := "unlimited"
+	if used.UsesRemaining != nil {
+		remaining = strconv.Itoa(*used.UsesRemaining)
+	}
+	s.AuditService.Log(
+		ctx,
+		&roleUUID,
+		nil,
+		nil,
+		"APPROLE_LOGIN",
+		"AppRole "+role.Name+" logged in from "+ip+" with secret ID "+used.Accessor.String()+" ("+remaining+" uses left), token valid until "+expiresAt.Format(time.RFC3339),
+	)
+	return token, expiresAt, nil
+}
+
+// VerifyRole makes sure the role an AppRole token was issued for was not
+// deleted. It is called by the AppRole auth middleware.
+func (s *AppRoleService) VerifyRole(ctx context.Context, roleID string) error {
+	if _, err := uuid.Parse(roleID); err != nil {
+		return errors.New("invalid token")
+	}
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil {
+		return err
+	}
+	if role == nil {
+		return errors.New("approle no longer exists")
+	}
+	return nil
+}
+
+// role loads a role the user can manage
+func (s *AppRoleService) role(ctx context.Context, userID string, roleID string) (*models.AppRole, error) {
+	if _, err := uuid.Parse(roleID); err != nil {
+		return nil, errors.New("approle not found")
+	}
+	role, err := s.repo.GetRoleByID(ctx, roleID)
+	if err != nil || role == nil {
+		return nil, errors.New("approle not found")
+	}
+	if err := s.checkManage(ctx, userID, role); err != nil {
+		return nil, errors.New("approle not found")
+	}
+	return role, nil
+}
+
+// checkManage makes sure the user manages every project the role reaches.
+// Service administrators manage all roles.
+func (s *AppRoleService) checkManage(ctx context.Context, userID string, role *models.AppRole) error {
+	if identity.IsAdmin(userID) {
+		return nil
+	}
+	for _, path := range role.Paths {
+		projectID, _, _ := strings.Cut(path, "/")
+		if _, err := uuid.Parse(projectID); err != nil {
+			return errors.New("path " + path + " must start with a project id")
+		}
+		if _, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
 	"github.com/akansha204/cryptex-secretservice/internal/models"
 	"github.com/akansha204/cryptex-secretservice/internal/repository"
 	"github.com/google/uuid"
@@ -38,6 +39,34 @@ func (a *AuditService) Log(
 		Message:   &message,
 		Timestamp: time.Now(),
 	}
+	a.write(ctx, logEntry)
+}
+
+// LogGrant logs an action a just-in-time access grant allowed, tied to that grant
+func (a *AuditService) LogGrant(
+	ctx context.Context,
+	userId, projectId, secretId, grantId *uuid.UUID,
+	action string,
+	message string,
+) {
+
+	logEntry := &models.AuditLog{
+		UserID:    valueOrNil(userId),
+		ProjectID: valueOrNil(projectId),
+		SecretID:  valueOrNil(secretId),
+		GrantID:   valueOrNil(grantId),
+		Action:    action,
+		Message:   &message,
+		Timestamp: time.Now(),
+	}
+	a.write(ctx, logEntry)
+}
+
+func (a *AuditService) write(ctx context.Context, logEntry *models.AuditLog) {
+	if id := identity.FromContext(ctx); id != nil {
+		logEntry.SourceIP = id.IP
+		_, logEntry.BreakGlass = id.BreakGlass[logEntry.ProjectID.String()]
+	}
 
 	if err := a.repo.Create(ctx, logEntry); err != nil {
 		log.Printf("[AUDIT ERROR] Failed to insert log: %v", err)
@@ -46,12 +75,15 @@ func (a *AuditService) Log(
 
 	// Terminal logging (safe — no sensitive values printed)
 	log.Printf(
-		"[AUDIT] action=%s user=%s project=%s secret=%s message=\"%s\"",
-		action,
+		"[AUDIT] action=%s user=%s project=%s secret=%s grant=%s break_glass=%t ip=%s message=\"%s\"",
+		logEntry.Action,
 		logEntry.UserID.String(),
 		logEntry.ProjectID.String(),
 		logEntry.SecretID.String(),
-		message,
+		logEntry.GrantID.String(),
+		logEntry.BreakGlass,
+		logEntry.SourceIP,
+		*logEntry.Message,
 	)
 }
 
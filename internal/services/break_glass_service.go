@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultBreakGlassTTL = time.Hour
+	maxBreakGlassTTL     = 4 * time.Hour
+)
+
+type BreakGlassService struct {
+	repo                *repository.BreakGlassRepository
+	Authorizer          *Authorizer
+	AuditService        *AuditService
+	NotificationService *NotificationService
+}
+
+func NewBreakGlassService(repo *repository.BreakGlassRepository, authorizer *Authorizer, auditService *AuditService, notificationService *NotificationService) *BreakGlassService {
+	return &BreakGlassService{
+		repo:                repo,
+		Authorizer:          authorizer,
+		AuditService:        auditService,
+		NotificationService: notificationService,
+	}
+}
+
+// OpenSession gives a service administrator admin rights on a project for a
+// while (default 1 hour, at most 4) when its owners cannot be reached. The
+// project's notification channels get a high priority alert for the owners
+// right away.
+func (s *BreakGlassService) OpenSession(ctx context.Context, userID string, projectID string, reason string, ttl *time.Duration) (*models.BreakGlassSession, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if !identity.IsAdmin(userID) || subjectFor(ctx, userID).ServiceAccount != nil {
+		return nil, errors.New("forbidden: only service administrators can break glass")
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("reason is required")
+	}
+	duration := defaultBreakGlassTTL
+	if ttl != nil {
+		if *ttl <= 0 || *ttl > maxBreakGlassTTL {
+			return nil, errors.New("ttl must be between 1s and " + maxBreakGlassTTL.String())
+		}
+		duration = *ttl
+	}
+
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	open, err := s.repo.GetActiveSession(ctx, userID, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if open != nil {
+		return nil, errors.New("a break-glass session is already open until " + open.ExpiresAt.Format(time.RFC3339))
+	}
+
+	now := time.Now()
+	session := &models.BreakGlassSession{
+		ID:        uuid.New(),
+		ProjectID: project.ID,
+		UserID:    userUUID,
+		Reason:    reason,
+		ExpiresAt: now.Add(duration),
+		CreatedAt: now,
+	}
+	if err := s.repo.CreateSession(ctx, session); err != nil {
+		return nil, err
+	}
+	identity.FromContext(ctx).MarkBreakGlass(project.ID.String(), session.ID)
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"BREAK_GLASS_OPENED",
+		"Break-glass session "+session.ID.String()+" opened until "+session.ExpiresAt.Format(time.RFC3339)+": "+reason,
+	)
+	s.NotificationService.NotifyUrgent(ctx, project.ID, NotifyBreakGlass, map[string]any{
+		"project":    project.Name,
+		"session_id": session.ID,
+		"user_id":    userID,
+		"reason":     reason,
+		"expires_at": session.ExpiresAt,
+		"owners":     s.owners(ctx, project),
+	})
+	return session, nil
+}
+
+// ListSessions shows the break-glass sessions of a project to service
+// administrators and the people who manage the project
+func (s *BreakGlassService) ListSessions(ctx context.Context, userID string, projectID string) ([]models.BreakGlassSession, error) {
+	if !identity.IsAdmin(userID) {
+		if _, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage); err != nil {
+			return nil, err
+		}
+	} else if _, err := s.Authorizer.Project(ctx, projectID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSessionsByProject(ctx, projectID)
+}
+
+// EndSession closes an open session before it expires. The administrator who
+// opened it and the project owners can end it.
+func (s *BreakGlassService) EndSession(ctx context.Context, userID string, projectID string, sessionID string) (*models.BreakGlassSession, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, errors.New("break-glass session not found")
+	}
+	session, err := s.repo.GetSessionByID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil || session.ProjectID != project.ID {
+		return nil, errors.New("break-glass session not found")
+	}
+	if session.UserID != userUUID {
+		if err := s.Authorizer.Check(ctx, project, userID, PermOwn); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	session.EndedAt = &now
+	session.EndedBy = &userUUID
+	ended, err := s.repo.EndSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if !ended {
+		return nil, errors.New("break-glass session already ended")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"BREAK_GLASS_ENDED",
+		"Break-glass session "+session.ID.String()+" of user "+session.UserID.String()+" ended",
+	)
+	s.NotificationService.Notify(ctx, project.ID, nil, NotifyBreakGlassEnd, map[string]any{
+		"project":    project.Name,
+		"session_id": session.ID,
+		"user_id":    session.UserID,
+		"ended_by":   userID,
+	})
+	return session, nil
+}
+
+// owners names the owners the alert is for; a lookup failure must not hold
+// the alert back
+func (s *BreakGlassService) owners(ctx context.Context, project *models.Project) []string {
+	owners, err := s.Authorizer.OwnerPrincipals(ctx, project)
+	if err != nil {
+		log.Printf("[BREAK GLASS ERROR] cannot list owners of project %s: %v", project.ID, err)
+	}
+	return owners
+}
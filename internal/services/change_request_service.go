@@ -0,0 +1,524 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const defaultChangeRequestTTL = 72 * time.Hour
+
+// what a change request proposes to do to a secret
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeRevoke = "revoke"
+	ChangeDelete = "delete"
+)
+
+// change request statuses; approved only lasts while the change is applied
+const (
+	ChangePending   = "pending"
+	ChangeApproved  = "approved"
+	ChangeApplied   = "applied"
+	ChangeRejected  = "rejected"
+	ChangeCancelled = "cancelled"
+	ChangeExpired   = "expired"
+	ChangeFailed    = "failed"
+)
+
+var changeStatuses = []string{ChangePending, ChangeApproved, ChangeApplied, ChangeRejected, ChangeCancelled, ChangeExpired, ChangeFailed}
+
+var errChangeRequestNotFound = errors.New("change request not found")
+
+// changePayload is the proposed value and settings of a create or update,
+// stored encrypted on the change request
+type changePayload struct {
+	Value    *string               `json:"value,omitempty"`
+	Generate *utils.GeneratePolicy `json:"generate,omitempty"`
+	Options  SecretOptions         `json:"options"`
+}
+
+type ChangeRequestService struct {
+	repo          *repository.ChangeRequestRepository
+	Authorizer    *Authorizer
+	SecretService *SecretService
+	AuditService  *AuditService
+}
+
+func NewChangeRequestService(repo *repository.ChangeRequestRepository, authorizer *Authorizer, secretService *SecretService, auditService *AuditService) *ChangeRequestService {
+	return &ChangeRequestService{
+		repo:          repo,
+		Authorizer:    authorizer,
+		SecretService: secretService,
+		AuditService:  auditService,
+	}
+}
+
+// ChangeProposal shows reviewers what a change request would do. The proposed
+// value itself is never returned.
+type ChangeProposal struct {
+	ValueChanged bool                  `json:"value_changed"`
+	Generate     *utils.GeneratePolicy `json:"generate,omitempty"`
+	Options      SecretOptions         `json:"options"`
+}
+
+// ChangeRequestDetail is a change request with its proposal and comments
+type ChangeRequestDetail struct {
+	models.ChangeRequest
+	Proposal *ChangeProposal               `json:"proposal,omitempty"`
+	Comments []models.ChangeRequestComment `json:"comments"`
+}
+
+// ListChangeRequests lists the change requests of a project, optionally only
+// those with the given status
+func (s *ChangeRequestService) ListChangeRequests(ctx context.Context, userID string, projectID string, status string) ([]models.ChangeRequest, error) {
+	if status != "" && !containsString(changeStatuses, status) {
+		return nil, errors.New("unknown status " + status)
+	}
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.expire(ctx, project); err != nil {
+		return nil, err
+	}
+	return s.repo.GetChangeRequestsByProject(ctx, projectID, status)
+}
+
+func (s *ChangeRequestService) GetChangeRequest(ctx context.Context, userID string, projectID string, requestID string) (*ChangeRequestDetail, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, err
+	}
+	request, err := s.changeRequest(ctx, project, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	detail := &ChangeRequestDetail{ChangeRequest: *request}
+	if request.Payload != nil {
+		payload, err := decryptPayload(request)
+		if err != nil {
+			return nil, err
+		}
+		detail.Proposal = &ChangeProposal{
+			ValueChanged: payload.Value != nil || payload.Generate != nil,
+			Generate:     payload.Generate,
+			Options:      payload.Options,
+		}
+	}
+	detail.Comments, err = s.repo.GetComments(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// ApproveChangeRequest approves a pending request of another member and
+// applies it. A request that cannot be applied, for example because the
+// secret changed since it was proposed, ends up failed.
+func (s *ChangeRequestService) ApproveChangeRequest(ctx context.Context, userID string, projectID string, requestID string, comment string) (*models.ChangeRequest, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, request, err := s.decide(ctx, userID, projectID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.RequestedBy == userUUID {
+		return nil, errors.New("forbidden: a change request must be approved by someone other than its requester")
+	}
+
+	now := time.Now()
+	request.Status = ChangeApproved
+	request.DecidedBy = &userUUID
+	request.DecidedAt = &now
+	ok, err := s.repo.Transition(ctx, request, ChangePending)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("change request is no longer pending")
+	}
+	if err := s.addComment(ctx, userUUID, request, comment); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&request.ProjectID,
+		request.SecretID,
+		"CHANGE_REQUEST_APPROVED",
+		"Change request "+request.ID.String()+" to "+request.Action+" secret "+request.SecretName+" approved",
+	)
+
+	secret, applyErr := s.SecretService.applyChange(ctx, project, request)
+	if applyErr != nil {
+		message := applyErr.Error()
+		request.Status = ChangeFailed
+		request.ApplyError = &message
+	} else {
+		request.Status = ChangeApplied
+		if secret != nil {
+			request.SecretID = &secret.ID
+		}
+	}
+	if _, err := s.repo.Transition(ctx, request, ChangeApproved); err != nil {
+		return nil, err
+	}
+
+	if applyErr != nil {
+		s.AuditService.Log(
+			ctx,
+			&userUUID,
+			&request.ProjectID,
+			request.SecretID,
+			"CHANGE_REQUEST_FAILED",
+			"Approved change request "+request.ID.String()+" could not be applied: "+applyErr.Error(),
+		)
+		return nil, errors.New("change request approved but not applied: " + applyErr.Error())
+	}
+	return request, nil
+}
+
+// RejectChangeRequest turns down a pending request of another member. A
+// requester withdraws their own request with CancelChangeRequest.
+func (s *ChangeRequestService) RejectChangeRequest(ctx context.Context, userID string, projectID string, requestID string, comment string) (*models.ChangeRequest, error) {
+	userUUID := uuid.MustParse(userID)
+
+	_, request, err := s.decide(ctx, userID, projectID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.RequestedBy == userUUID {
+		return nil, errors.New("cancel your own change request instead of rejecting it")
+	}
+
+	now := time.Now()
+	request.Status = ChangeRejected
+	request.DecidedBy = &userUUID
+	request.DecidedAt = &now
+	ok, err := s.repo.Transition(ctx, request, ChangePending)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("change request is no longer pending")
+	}
+	if err := s.addComment(ctx, userUUID, request, comment); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&request.ProjectID,
+		request.SecretID,
+		"CHANGE_REQUEST_REJECTED",
+		"Change request "+request.ID.String()+" to "+request.Action+" secret "+request.SecretName+" rejected",
+	)
+	return request, nil
+}
+
+// CancelChangeRequest lets the requester withdraw a pending request
+func (s *ChangeRequestService) CancelChangeRequest(ctx context.Context, userID string, projectID string, requestID string) (*models.ChangeRequest, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, err
+	}
+	request, err := s.changeRequest(ctx, project, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request.RequestedBy != userUUID {
+		return nil, errors.New("forbidden: only the requester can cancel a change request")
+	}
+	if request.Status != ChangePending {
+		return nil, errors.New("change request is " + request.Status + ", not pending")
+	}
+
+	now := time.Now()
+	request.Status = ChangeCancelled
+	request.DecidedBy = &userUUID
+	request.DecidedAt = &now
+	ok, err := s.repo.Transition(ctx, request, ChangePending)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errors.New("change request is no longer pending")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&request.ProjectID,
+		request.SecretID,
+		"CHANGE_REQUEST_CANCELLED",
+		"Change request "+request.ID.String()+" to "+request.Action+" secret "+request.SecretName+" cancelled",
+	)
+	return request, nil
+}
+
+// CommentChangeRequest adds a comment; anyone who can see the project can
+// discuss its change requests
+func (s *ChangeRequestService) CommentChangeRequest(ctx context.Context, userID string, projectID string, requestID string, body string) (*models.ChangeRequestComment, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if strings.TrimSpace(body) == "" {
+		return nil, errors.New("comment is required")
+	}
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, err
+	}
+	request, err := s.changeRequest(ctx, project, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.ChangeRequestComment{
+		ID:              uuid.New(),
+		ChangeRequestID: request.ID,
+		UserID:          userUUID,
+		Body:            body,
+		CreatedAt:       time.Now(),
+	}
+	if err := s.repo.CreateComment(ctx, comment); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&request.ProjectID,
+		request.SecretID,
+		"CHANGE_REQUEST_COMMENTED",
+		"Comment added to change request "+request.ID.String(),
+	)
+	return comment, nil
+}
+
+// decide loads a pending request the user may approve or reject
+func (s *ChangeRequestService) decide(ctx context.Context, userID string, projectID string, requestID string) (*models.Project, *models.ChangeRequest, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, nil, err
+	}
+	request, err := s.changeRequest(ctx, project, requestID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.Authorizer.CheckSecret(ctx, project, userID, request.SecretName, PermApprove); err != nil {
+		return nil, nil, err
+	}
+	if request.Status != ChangePending {
+		return nil, nil, errors.New("change request is " + request.Status + ", not pending")
+	}
+	return project, request, nil
+}
+
+// changeRequest loads a request of the project, expiring the project's
+// overdue requests first
+func (s *ChangeRequestService) changeRequest(ctx context.Context, project *models.Project, requestID string) (*models.ChangeRequest, error) {
+	if _, err := uuid.Parse(requestID); err != nil {
+		return nil, errChangeRequestNotFound
+	}
+	if err := s.expire(ctx, project); err != nil {
+		return nil, err
+	}
+	request, err := s.repo.GetChangeRequestByID(ctx, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if request == nil || request.ProjectID != project.ID {
+		return nil, errChangeRequestNotFound
+	}
+	return request, nil
+}
+
+// expire marks the overdue pending requests of a project as expired and
+// audits each of them
+func (s *ChangeRequestService) expire(ctx context.Context, project *models.Project) error {
+	expired, err := s.repo.ExpirePending(ctx, project.ID.String())
+	if err != nil {
+		return err
+	}
+	for _, request := range expired {
+		s.AuditService.Log(
+			ctx,
+			&request.RequestedBy,
+			&request.ProjectID,
+			request.SecretID,
+			"CHANGE_REQUEST_EXPIRED",
+			"Change request "+request.ID.String()+" to "+request.Action+" secret "+request.SecretName+" expired without a decision",
+		)
+	}
+	return nil
+}
+
+func (s *ChangeRequestService) addComment(ctx context.Context, userUUID uuid.UUID, request *models.ChangeRequest, body string) error {
+	if strings.TrimSpace(body) == "" {
+		return nil
+	}
+	return s.repo.CreateComment(ctx, &models.ChangeRequestComment{
+		ID:              uuid.New(),
+		ChangeRequestID: request.ID,
+		UserID:          userUUID,
+		Body:            body,
+		CreatedAt:       time.Now(),
+	})
+}
+
+// propose stores a change the user is allowed to make as a pending change
+// request of the project and returns the PendingApprovalError for it
+func (s *SecretService) propose(ctx context.Context, userUUID uuid.UUID, project *models.Project, secret *models.Secret, name string, action string, payload *changePayload) error {
+	now := time.Now()
+	request := &models.ChangeRequest{
+		ID:          uuid.New(),
+		ProjectID:   project.ID,
+		SecretName:  name,
+		Action:      action,
+		Status:      ChangePending,
+		RequestedBy: userUUID,
+		ExpiresAt:   now.Add(changeRequestTTL()),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if secret != nil {
+		version := secret.Version
+		request.SecretID = &secret.ID
+		request.BaseVersion = &version
+	}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		encrypted, err := utils.Encrypt(string(data))
+		if err != nil {
+			return err
+		}
+		request.Payload = &encrypted
+	}
+
+	if err := s.changeRequestRepo.CreateChangeRequest(ctx, request); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&request.ProjectID,
+		request.SecretID,
+		"CHANGE_REQUEST_CREATED",
+		"Change request "+request.ID.String()+" to "+action+" secret "+name+" waits for approval until "+request.ExpiresAt.Format(time.RFC3339),
+	)
+	return &PendingApprovalError{Request: request}
+}
+
+// applyChange makes the change of an approved request on behalf of its
+// requester. Changes to a secret that moved on since they were proposed are
+// refused rather than overwriting the newer version.
+func (s *SecretService) applyChange(ctx context.Context, project *models.Project, request *models.ChangeRequest) (*models.Secret, error) {
+	var payload changePayload
+	if request.Payload != nil {
+		decrypted, err := decryptPayload(request)
+		if err != nil {
+			return nil, err
+		}
+		payload = *decrypted
+	}
+
+	if request.Action == ChangeCreate {
+		value := ""
+		if payload.Value != nil {
+			value = *payload.Value
+		}
+		return s.createSecret(ctx, request.RequestedBy, project, request.SecretName, value, payload.Generate, payload.Options)
+	}
+
+	if request.SecretID == nil {
+		return nil, errors.New("change request has no secret")
+	}
+	secret, err := projectSecret(ctx, s.secretRepo, project, request.SecretID.String())
+	if err != nil {
+		return nil, err
+	}
+	if request.BaseVersion != nil && secret.Version != *request.BaseVersion {
+		return nil, errors.New("secret changed since the request was made, propose the change again")
+	}
+
+	switch request.Action {
+	case ChangeUpdate:
+		if secret.Revoked {
+			return nil, errors.New("cannot update a revoked secret")
+		}
+		return s.updateSecret(ctx, request.RequestedBy, project, secret, payload.Value, payload.Generate, payload.Options)
+	case ChangeRevoke:
+		return secret, s.revokeSecret(ctx, request.RequestedBy, secret)
+	case ChangeDelete:
+		return secret, s.deleteSecret(ctx, request.RequestedBy, secret)
+	}
+	return nil, errors.New("unknown change " + request.Action)
+}
+
+// checkProposal catches invalid settings when a change is proposed instead of
+// when it is approved
+func checkProposal(project *models.Project, base *models.Secret, generate *utils.GeneratePolicy, opts SecretOptions) error {
+	if generate != nil {
+		if _, err := utils.GenerateSecret(*generate); err != nil {
+			return err
+		}
+	}
+	if opts.TTL != nil || opts.ExpiresAt != nil || opts.NotBefore != nil {
+		if _, _, err := resolveLifetime(project, opts, time.Now()); err != nil {
+			return err
+		}
+	}
+	scratch := *base
+	if err := applyLeaseOptions(&scratch, opts); err != nil {
+		return err
+	}
+	if err := applyAllowedCIDRs(&scratch, opts); err != nil {
+		return err
+	}
+	if opts.MaxReads != nil && *opts.MaxReads < 1 {
+		return errors.New("max_reads must be at least 1")
+	}
+	return nil
+}
+
+func decryptPayload(request *models.ChangeRequest) (*changePayload, error) {
+	plaintext, err := utils.Decrypt(*request.Payload)
+	if err != nil {
+		return nil, err
+	}
+	var payload changePayload
+	if err := json.Unmarshal([]byte(plaintext), &payload); err != nil {
+		return nil, err
+	}
+	return &payload, nil
+}
+
+// changeRequestTTL is how long a change request waits for a decision
+// (CHANGE_REQUEST_TTL, default 72 hours)
+func changeRequestTTL() time.Duration {
+	if raw := os.Getenv("CHANGE_REQUEST_TTL"); raw != "" {
+		if d, err := utils.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultChangeRequestTTL
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	LeaseReasonRevoked = "revoked"
+	LeaseReasonExpired = "expired"
+)
+
+// used when a lease-enabled secret does not set its own max ttl
+const defaultLeaseMaxTTL = 24 * time.Hour
+
+type LeaseService struct {
+	leaseRepo      *repository.LeaseRepository
+	secretRepo     *repository.SecretRepository
+	Authorizer     *Authorizer
+	AuditService   *AuditService
+	WebhookService *WebhookService
+}
+
+func NewLeaseService(leaseRepo *repository.LeaseRepository, secretRepo *repository.SecretRepository, authorizer *Authorizer, auditService *AuditService, webhookService *WebhookService) *LeaseService {
+	return &LeaseService{
+		leaseRepo:      leaseRepo,
+		secretRepo:     secretRepo,
+		Authorizer:     authorizer,
+		AuditService:   auditService,
+		WebhookService: webhookService,
+	}
+}
+
+// Issue creates a lease for one read of a lease-enabled secret
+func (s *LeaseService) Issue(ctx context.Context, userID uuid.UUID, secret *models.Secret) (*models.Lease, error) {
+	if secret.LeaseTTL == nil {
+		return nil, errors.New("secret is not lease-enabled")
+	}
+
+	suffix, err := utils.RandomToken("", 12)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	ttl := time.Duration(*secret.LeaseTTL) * time.Second
+	maxTTL := defaultLeaseMaxTTL
+	if secret.LeaseMaxTTL != nil {
+		maxTTL = time.Duration(*secret.LeaseMaxTTL) * time.Second
+	}
+	if maxTTL < ttl {
+		maxTTL = ttl
+	}
+
+	lease := &models.Lease{
+		ID:           secret.ProjectID.String() + "/" + secret.Name + "/" + suffix,
+		ProjectID:    secret.ProjectID,
+		SecretID:     secret.ID,
+		UserID:       userID,
+		IssuedAt:     now,
+		ExpiresAt:    now.Add(ttl),
+		MaxExpiresAt: now.Add(maxTTL),
+	}
+	// a lease never outlives the secret it was issued for
+	if secret.ExpiresAt != nil && secret.ExpiresAt.Before(lease.MaxExpiresAt) {
+		lease.MaxExpiresAt = *secret.ExpiresAt
+		if lease.ExpiresAt.After(lease.MaxExpiresAt) {
+			lease.ExpiresAt = lease.MaxExpiresAt
+		}
+	}
+
+	if err := s.leaseRepo.CreateLease(ctx, lease); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userID,
+		&secret.ProjectID,
+		&secret.ID,
+		"ISSUE_LEASE",
+		"Lease "+lease.ID+" issued until "+lease.ExpiresAt.Format(time.RFC3339),
+	)
+
+	return lease, nil
+}
+
+// Renew extends a live lease by increment (or by the secret's lease ttl),
+// never past its max ttl
+func (s *LeaseService) Renew(ctx context.Context, userID string, leaseID string, increment *time.Duration) (*models.Lease, error) {
+	userUUID := uuid.MustParse(userID)
+
+	lease, err := s.holdableLease(ctx, userID, leaseID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := s.secretRepo.GetSecretByID(ctx, lease.SecretID.String())
+	if err != nil || secret == nil || secret.Revoked || secret.LeaseTTL == nil {
+		return nil, errors.New("lease can no longer be renewed")
+	}
+
+	extendBy := time.Duration(*secret.LeaseTTL) * time.Second
+	if increment != nil {
+		if *increment <= 0 {
+			return nil, errors.New("increment must be positive")
+		}
+		extendBy = *increment
+	}
+
+	expiresAt := time.Now().Add(extendBy)
+	if expiresAt.After(lease.MaxExpiresAt) {
+		expiresAt = lease.MaxExpiresAt
+	}
+
+	renewed, err := s.leaseRepo.RenewLease(ctx, lease.ID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !renewed {
+		return nil, errors.New("lease is expired or revoked")
+	}
+	lease.ExpiresAt = expiresAt
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&lease.ProjectID,
+		&lease.SecretID,
+		"RENEW_LEASE",
+		"Lease "+lease.ID+" renewed until "+expiresAt.Format(time.RFC3339),
+	)
+
+	return lease, nil
+}
+
+// Revoke ends a single lease; the holder or a project member who may revoke can end it
+func (s *LeaseService) Revoke(ctx context.Context, userID string, leaseID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	if _, err := s.holdableLease(ctx, userID, leaseID); err != nil {
+		return err
+	}
+
+	lease, err := s.leaseRepo.RevokeLease(ctx, leaseID, LeaseReasonRevoked)
+	if err != nil {
+		return err
+	}
+	if lease == nil {
+		return errors.New("lease is already revoked")
+	}
+
+	s.leaseEnded(ctx, &userUUID, *lease)
+	return nil
+}
+
+// ListLeases lists the live leases of a project whose secret name starts with prefix
+func (s *LeaseService) ListLeases(ctx context.Context, userID string, projectID string, prefix string) ([]models.Lease, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, err
+	}
+	return s.leaseRepo.GetLeasesByPrefix(ctx, project.ID.String()+"/"+prefix, false)
+}
+
+// RevokePrefix revokes every live lease of a project whose secret name starts with prefix
+func (s *LeaseService) RevokePrefix(ctx context.Context, userID string, projectID string, prefix string) (int, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermRevoke)
+	if err != nil {
+		return 0, err
+	}
+
+	leases, err := s.leaseRepo.RevokeLeasesByPrefix(ctx, project.ID.String()+"/"+prefix, LeaseReasonRevoked)
+	if err != nil {
+		return 0, err
+	}
+	for _, lease := range leases {
+		s.leaseEnded(ctx, &userUUID, lease)
+	}
+	return len(leases), nil
+}
+
+// RevokeForSecret revokes every live lease of a secret, e.g. when the secret itself is revoked
+func (s *LeaseService) RevokeForSecret(ctx context.Context, userID *uuid.UUID, secretID uuid.UUID) error {
+	leases, err := s.leaseRepo.RevokeLeasesBySecret(ctx, secretID.String(), LeaseReasonRevoked)
+	if err != nil {
+		return err
+	}
+	for _, lease := range leases {
+		s.leaseEnded(ctx, userID, lease)
+	}
+	return nil
+}
+
+// ExpireDue marks leases that ran out and notifies their issuers.
+// It is driven by the lease sweeper in main.
+func (s *LeaseService) ExpireDue(ctx context.Context) (int, error) {
+	leases, err := s.leaseRepo.ExpireLeases(ctx, time.Now())
+	if err != nil {
+		return 0, err
+	}
+	for _, lease := range leases {
+		s.leaseEnded(ctx, nil, lease)
+	}
+	return len(leases), nil
+}
+
+// holdableLease loads a lease the user may act on: their own, or any lease
+// of a project where they may revoke
+func (s *LeaseService) holdableLease(ctx context.Context, userID string, leaseID string) (*models.Lease, error) {
+	lease, err := s.leaseRepo.GetLeaseByID(ctx, leaseID)
+	if err != nil || lease == nil {
+		return nil, errors.New("lease not found")
+	}
+	if lease.RevokedAt != nil {
+		return nil, errors.New("lease is expired or revoked")
+	}
+	if lease.UserID.String() == userID {
+		return lease, nil
+	}
+	if _, err := s.Authorizer.Authorize(ctx, userID, lease.ProjectID.String(), PermRevoke); err != nil {
+		return nil, errors.New("lease not found")
+	}
+	return lease, nil
+}
+
+// leaseEnded audits the end of a lease and tells the issuing system through the project webhooks
+func (s *LeaseService) leaseEnded(ctx context.Context, userID *uuid.UUID, lease models.Lease) {
+	reason := LeaseReasonRevoked
+	if lease.RevokeReason != nil {
+		reason = *lease.RevokeReason
+	}
+
+	action, event := "REVOKE_LEASE", EventLeaseRevoked
+	if reason == LeaseReasonExpired {
+		action, event = "EXPIRE_LEASE", EventLeaseExpired
+	}
+
+	s.AuditService.Log(
+		ctx,
+		userID,
+		&lease.ProjectID,
+		&lease.SecretID,
+		action,
+		"Lease "+lease.ID+" ended ("+reason+") after "+strconv.Itoa(int(time.Since(lease.IssuedAt).Seconds()))+"s",
+	)
+	s.WebhookService.Emit(ctx, lease.ProjectID, &lease.SecretID, userID, event, map[string]any{
+		"lease_id":  lease.ID,
+		"holder_id": lease.UserID,
+		"issued_at": lease.IssuedAt.UTC(),
+		"reason":    reason,
+	})
+}
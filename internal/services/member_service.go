@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/google/uuid"
+)
+
+type MemberService struct {
+	repo         *repository.MemberRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+}
+
+func NewMemberService(repo *repository.MemberRepository, authorizer *Authorizer, auditService *AuditService) *MemberService {
+	return &MemberService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+	}
+}
+
+// ListMembers lists the members of a project, starting with the user owning it
+func (s *MemberService) ListMembers(ctx context.Context, userID string, projectID string) ([]models.ProjectMember, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.repo.GetMembersByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if OwnerUserID(project) == "" {
+		// team and org owners are listed on the project itself
+		return members, nil
+	}
+	owner := models.ProjectMember{
+		ProjectID: project.ID,
+		UserID:    project.UserID,
+		Role:      RoleOwner,
+		CreatedAt: project.CreatedAt,
+		UpdatedAt: project.CreatedAt,
+	}
+	return append([]models.ProjectMember{owner}, members...), nil
+}
+
+// AddMember invites a user to a project with a role. Admins can add writers,
+// readers and metadata-only members; only owners can add admins and owners.
+func (s *MemberService) AddMember(ctx context.Context, userID string, projectID string, memberID string, role string) (*models.ProjectMember, error) {
+	userUUID := uuid.MustParse(userID)
+
+	memberUUID, err := uuid.Parse(memberID)
+	if err != nil {
+		return nil, errors.New("invalid user_id")
+	}
+	if !ValidRole(role) {
+		return nil, errors.New("invalid role")
+	}
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, memberPermission(role))
+	if err != nil {
+		return nil, err
+	}
+	if OwnerUserID(project) == memberUUID.String() {
+		return nil, errors.New("user is already the owner of this project")
+	}
+
+	member := &models.ProjectMember{
+		ProjectID: project.ID,
+		UserID:    memberUUID,
+		Role:      role,
+		AddedBy:   userUUID,
+	}
+	added, err := s.repo.AddMember(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, errors.New("user is already a member of this project")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"ADD_MEMBER",
+		"User "+memberID+" added as "+role,
+	)
+	return member, nil
+}
+
+// UpdateMember changes the role of a member
+func (s *MemberService) UpdateMember(ctx context.Context, userID string, projectID string, memberID string, role string) (*models.ProjectMember, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if !ValidRole(role) {
+		return nil, errors.New("invalid role")
+	}
+
+	project, member, err := s.projectMember(ctx, userID, projectID, memberID)
+	if err != nil {
+		return nil, err
+	}
+	// both the current and the new role must be within the caller's reach
+	if err := s.Authorizer.Check(ctx, project, userID, memberPermission(member.Role)); err != nil {
+		return nil, err
+	}
+	if err := s.Authorizer.Check(ctx, project, userID, memberPermission(role)); err != nil {
+		return nil, err
+	}
+
+	previous := member.Role
+	if err := s.repo.UpdateMemberRole(ctx, projectID, memberID, role); err != nil {
+		return nil, err
+	}
+	member.Role = role
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"UPDATE_MEMBER_ROLE",
+		"User "+memberID+" changed from "+previous+" to "+role,
+	)
+	return member, nil
+}
+
+// RemoveMember removes a member from a project; members can always remove themselves
+func (s *MemberService) RemoveMember(ctx context.Context, userID string, projectID string, memberID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, member, err := s.projectMember(ctx, userID, projectID, memberID)
+	if err != nil {
+		return err
+	}
+	if memberID != userID {
+		if err := s.Authorizer.Check(ctx, project, userID, memberPermission(member.Role)); err != nil {
+			return err
+		}
+	}
+
+	if err := s.repo.RemoveMember(ctx, projectID, memberID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"REMOVE_MEMBER",
+		"User "+memberID+" ("+member.Role+") removed",
+	)
+	return nil
+}
+
+// projectMember loads a project the user can see and one of its member rows.
+// The owning user has no row, so it can neither be changed nor removed here.
+func (s *MemberService) projectMember(ctx context.Context, userID string, projectID string, memberID string) (*models.Project, *models.ProjectMember, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+	if err != nil {
+		return nil, nil, err
+	}
+	if OwnerUserID(project) == memberID {
+		return nil, nil, errors.New("the project owner cannot be changed or removed, transfer the project instead")
+	}
+
+	member, err := s.repo.GetMember(ctx, projectID, memberID)
+	if err != nil || member == nil {
+		return nil, nil, errors.New("member not found")
+	}
+	return project, member, nil
+}
+
+// memberPermission is what it takes to grant, change or remove a role
+func memberPermission(role string) Permission {
+	if role == RoleOwner || role == RoleAdmin {
+		return PermOwn
+	}
+	return PermManage
+}
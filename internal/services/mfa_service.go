@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const defaultStepUpMaxAge = 5 * time.Minute
+
+// amr values (RFC 8176) that show the user signed in with a second factor
+var mfaMethods = []string{"mfa", "otp", "hwk", "sc", "fpt", "face", "iris", "retina"}
+
+type MFAService struct {
+	repo         *repository.MFARepository
+	AuditService *AuditService
+}
+
+func NewMFAService(repo *repository.MFARepository, auditService *AuditService) *MFAService {
+	return &MFAService{
+		repo:         repo,
+		AuditService: auditService,
+	}
+}
+
+// TOTPEnrollment is what an authenticator app needs to add the factor
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+}
+
+// EnrollTOTP starts enrolling a TOTP factor. It is only used for step-up once
+// ConfirmTOTP verified a first code. Enrolling again replaces an unconfirmed factor.
+func (s *MFAService) EnrollTOTP(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	if err := requirePerson(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.repo.ReplaceUnconfirmedFactor(ctx, &models.MFAFactor{
+		ID:        uuid.New(),
+		UserID:    uuid.MustParse(userID),
+		Kind:      "totp",
+		Secret:    encrypted,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !stored {
+		return nil, errors.New("a TOTP factor is already enrolled, remove it first")
+	}
+
+	account := userID
+	if id := identity.FromContext(ctx); id != nil && id.Email != "" {
+		account = id.Email
+	}
+	return &TOTPEnrollment{
+		Secret: secret,
+		URL:    utils.TOTPURL("Cryptex", account, secret),
+	}, nil
+}
+
+func (s *MFAService) ConfirmTOTP(ctx context.Context, userID string, code string) error {
+	userUUID := uuid.MustParse(userID)
+
+	factor, err := s.repo.GetFactorByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if factor == nil {
+		return errors.New("no TOTP factor to confirm, enroll first")
+	}
+	if factor.ConfirmedAt != nil {
+		return errors.New("the TOTP factor is already confirmed")
+	}
+	if err := s.useCode(ctx, factor, code, true); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"MFA_ENROLLED",
+		"TOTP factor enrolled",
+	)
+	return nil
+}
+
+// GetFactor returns the user's factor, or nil when none is enrolled
+func (s *MFAService) GetFactor(ctx context.Context, userID string) (*models.MFAFactor, error) {
+	return s.repo.GetFactorByUser(ctx, userID)
+}
+
+// RemoveTOTP removes the user's factor. A confirmed factor can only be
+// removed with a valid code from it.
+func (s *MFAService) RemoveTOTP(ctx context.Context, userID string, code string) error {
+	userUUID := uuid.MustParse(userID)
+
+	factor, err := s.repo.GetFactorByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if factor == nil {
+		return errors.New("no TOTP factor enrolled")
+	}
+	if factor.ConfirmedAt != nil {
+		if err := s.useCode(ctx, factor, code, false); err != nil {
+			return err
+		}
+	}
+	if err := s.repo.DeleteFactor(ctx, factor.ID.String()); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"MFA_REMOVED",
+		"TOTP factor removed",
+	)
+	return nil
+}
+
+// StepUp makes sure the caller proved a second factor before a sensitive
+// secret is read: a token whose amr shows MFA and whose auth_time is recent
+// enough (STEP_UP_MAX_AGE, default 5 minutes), or a fresh code of their
+// enrolled TOTP factor in X-Cryptex-TOTP. Failures are audited.
+func (s *MFAService) StepUp(ctx context.Context, userID string, secret *models.Secret) error {
+	userUUID := uuid.MustParse(userID)
+
+	fail := func(reason string) error {
+		s.AuditService.Log(
+			ctx,
+			&userUUID,
+			&secret.ProjectID,
+			&secret.ID,
+			"STEP_UP_FAILED",
+			"Step-up for sensitive secret "+secret.Name+" failed: "+reason,
+		)
+		return errors.New("step-up required: " + reason)
+	}
+
+	id := identity.FromContext(ctx)
+	if id == nil || id.UserID != userID {
+		return fail("no interactive session")
+	}
+	if id.ServiceAccount != nil {
+		return fail("machine identities cannot step up")
+	}
+
+	if id.AuthTime != nil && time.Since(*id.AuthTime) <= stepUpMaxAge() {
+		for _, method := range id.AuthMethods {
+			if containsString(mfaMethods, method) {
+				return nil
+			}
+		}
+	}
+
+	if id.TOTPCode == "" {
+		return fail("sign in again with MFA or send a TOTP code in X-Cryptex-TOTP")
+	}
+	factor, err := s.repo.GetFactorByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if factor == nil || factor.ConfirmedAt == nil {
+		return fail("no TOTP factor enrolled")
+	}
+	if err := s.useCode(ctx, factor, id.TOTPCode, false); err != nil {
+		return fail(err.Error())
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"STEP_UP",
+		"Stepped up with TOTP to read sensitive secret "+secret.Name,
+	)
+	return nil
+}
+
+// useCode accepts a TOTP code of the factor once; confirm also confirms the factor
+func (s *MFAService) useCode(ctx context.Context, factor *models.MFAFactor, code string, confirm bool) error {
+	secret, err := utils.Decrypt(factor.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := utils.VerifyTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return errors.New("invalid TOTP code")
+	}
+	used, err := s.repo.UseStep(ctx, factor.ID.String(), step, confirm)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return errors.New("TOTP code was already used")
+	}
+	return nil
+}
+
+// requirePerson refuses machine identities, which cannot hold a second factor
+func requirePerson(ctx context.Context, userID string) error {
+	if subjectFor(ctx, userID).ServiceAccount != nil {
+		return errors.New("forbidden: machine identities cannot enroll MFA")
+	}
+	return nil
+}
+
+func stepUpMaxAge() time.Duration {
+	if raw := os.Getenv("STEP_UP_MAX_AGE"); raw != "" {
+		if d, err := utils.ParseDuration(raw); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultStepUpMaxAge
+}
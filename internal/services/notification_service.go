@@ -0,0 +1,366 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	NotifySecretExpiring = "secret.expiring"
+	NotifySecretExpired  = "secret.expired"
+	NotifySecretRevoked  = "secret.revoked"
+	NotifySecretDeleted  = "secret.deleted"
+	NotifyRotationFailed = "secret.rotation_failed"
+	NotifyBreakGlass     = "project.break_glass"
+	NotifyBreakGlassEnd  = "project.break_glass_ended"
+)
+
+const (
+	notificationTimeout = 10 * time.Second
+
+	// how far back CheckExpiring looks for secrets that expired since the last run
+	expiredNotificationWindow = 24 * time.Hour
+)
+
+type NotificationService struct {
+	repo         *repository.NotificationRepository
+	Authorizer   *Authorizer
+	secretRepo   *repository.SecretRepository
+	AuditService *AuditService
+	client       *http.Client
+}
+
+func NewNotificationService(repo *repository.NotificationRepository, authorizer *Authorizer, secretRepo *repository.SecretRepository, auditService *AuditService) *NotificationService {
+	return &NotificationService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		secretRepo:   secretRepo,
+		AuditService: auditService,
+		client:       &http.Client{Timeout: notificationTimeout},
+	}
+}
+
+type Notification struct {
+	Event      string         `json:"event"`
+	Priority   string         `json:"priority,omitempty"` // "high" for alerts that need someone now
+	ProjectID  uuid.UUID      `json:"project_id"`
+	SecretID   *uuid.UUID     `json:"secret_id,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// ------------------------------------------------------------
+// Channels
+// ------------------------------------------------------------
+
+func (s *NotificationService) CreateChannel(
+	ctx context.Context,
+	userID string,
+	projectID string,
+	name string,
+	webhookURL string,
+	signingSecret *string,
+) (*models.NotificationChannel, error) {
+
+	userUUID := uuid.MustParse(userID)
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, errors.New("webhook_url must be an http(s) url")
+	}
+
+	channel := &models.NotificationChannel{
+		ProjectID:  project.ID,
+		Name:       name,
+		WebhookURL: webhookURL,
+		Enabled:    true,
+		CreatedAt:  time.Now(),
+	}
+	if signingSecret != nil {
+		encrypted, err := utils.Encrypt(*signingSecret)
+		if err != nil {
+			return nil, err
+		}
+		channel.SigningSecret = &encrypted
+	}
+
+	if err := s.repo.CreateChannel(ctx, channel); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"CREATE_NOTIFICATION_CHANNEL",
+		"Notification channel "+name+" created",
+	)
+
+	return channel, nil
+}
+
+func (s *NotificationService) ListChannels(ctx context.Context, userID string, projectID string) ([]models.NotificationChannel, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetChannelsByProject(ctx, project.ID.String())
+}
+
+func (s *NotificationService) DeleteChannel(ctx context.Context, userID string, projectID string, channelID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return err
+	}
+
+	channel, err := s.repo.GetChannelByID(ctx, channelID)
+	if err != nil || channel == nil || channel.ProjectID != project.ID {
+		return errors.New("notification channel not found")
+	}
+
+	if err := s.repo.DeleteChannel(ctx, channelID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"DELETE_NOTIFICATION_CHANNEL",
+		"Notification channel "+channel.Name+" deleted",
+	)
+	return nil
+}
+
+// ------------------------------------------------------------
+// Sending
+// ------------------------------------------------------------
+
+// Notify sends the event to every enabled channel of the project.
+// Delivery happens in the background and never fails the caller.
+func (s *NotificationService) Notify(ctx context.Context, projectID uuid.UUID, secretID *uuid.UUID, event string, data map[string]any) {
+	s.notify(ctx, Notification{
+		Event:      event,
+		ProjectID:  projectID,
+		SecretID:   secretID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+}
+
+// NotifyUrgent behaves like Notify but marks the notification high priority
+func (s *NotificationService) NotifyUrgent(ctx context.Context, projectID uuid.UUID, event string, data map[string]any) {
+	s.notify(ctx, Notification{
+		Event:      event,
+		Priority:   "high",
+		ProjectID:  projectID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+}
+
+func (s *NotificationService) notify(ctx context.Context, notification Notification) {
+	channels, payload, ok := s.prepare(ctx, notification)
+	if !ok {
+		return
+	}
+	for _, channel := range channels {
+		go s.deliver(channel, notification.Event, payload)
+	}
+}
+
+// prepare loads the enabled channels of the notification's project and
+// encodes the notification for them
+func (s *NotificationService) prepare(ctx context.Context, notification Notification) ([]models.NotificationChannel, []byte, bool) {
+	projectID, event := notification.ProjectID, notification.Event
+	channels, err := s.repo.GetChannelsByProject(ctx, projectID.String())
+	if err != nil {
+		log.Printf("[NOTIFY ERROR] cannot load channels for project %s: %v", projectID, err)
+		return nil, nil, false
+	}
+
+	payload, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("[NOTIFY ERROR] cannot encode %s: %v", event, err)
+		return nil, nil, false
+	}
+
+	var enabled []models.NotificationChannel
+	for _, channel := range channels {
+		if channel.Enabled {
+			enabled = append(enabled, channel)
+		}
+	}
+	return enabled, payload, true
+}
+
+// NotifyOnce behaves like Notify but sends nothing if an alert with the same
+// dedup key was already sent. The key is claimed before sending so parallel
+// runs do not both send it, and released again when no channel received the
+// alert, so the next run retries it.
+func (s *NotificationService) NotifyOnce(ctx context.Context, projectID uuid.UUID, secretID *uuid.UUID, event string, dedupKey string, data map[string]any) {
+	fresh, err := s.repo.RecordOnce(ctx, &models.NotificationRecord{
+		ProjectID: projectID,
+		SecretID:  valueOrNil(secretID),
+		Event:     event,
+		DedupKey:  dedupKey,
+		SentAt:    time.Now(),
+	})
+	if err != nil {
+		log.Printf("[NOTIFY ERROR] cannot record %s: %v", dedupKey, err)
+		return
+	}
+	if !fresh {
+		return
+	}
+
+	channels, payload, ok := s.prepare(ctx, Notification{
+		Event:      event,
+		ProjectID:  projectID,
+		SecretID:   secretID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if !ok {
+		s.release(dedupKey)
+		return
+	}
+
+	go func() {
+		var wg sync.WaitGroup
+		var delivered atomic.Int32
+		for _, channel := range channels {
+			wg.Add(1)
+			go func(channel models.NotificationChannel) {
+				defer wg.Done()
+				if s.deliver(channel, event, payload) {
+					delivered.Add(1)
+				}
+			}(channel)
+		}
+		wg.Wait()
+		if delivered.Load() == 0 {
+			s.release(dedupKey)
+		}
+	}()
+}
+
+// release lets an alert that reached no channel be sent again
+func (s *NotificationService) release(dedupKey string) {
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	if err := s.repo.ReleaseRecord(ctx, dedupKey); err != nil {
+		log.Printf("[NOTIFY ERROR] cannot release %s: %v", dedupKey, err)
+	}
+}
+
+// deliver posts the payload to one channel and reports whether it accepted it
+func (s *NotificationService) deliver(channel models.NotificationChannel, event string, payload []byte) bool {
+	// the request context may already be gone, so delivery uses its own
+	ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, channel.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		log.Printf("[NOTIFY ERROR] channel=%s event=%s: %v", channel.ID, event, err)
+		return false
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if channel.SigningSecret != nil {
+		key, err := utils.Decrypt(*channel.SigningSecret)
+		if err != nil {
+			log.Printf("[NOTIFY ERROR] channel=%s cannot decrypt signing secret: %v", channel.ID, err)
+			return false
+		}
+		req.Header.Set(utils.SignatureHeader, utils.SignPayload([]byte(key), payload))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		log.Printf("[NOTIFY ERROR] channel=%s event=%s: %v", channel.ID, event, err)
+		return false
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		log.Printf("[NOTIFY ERROR] channel=%s event=%s: status %d", channel.ID, event, resp.StatusCode)
+		return false
+	}
+	return true
+}
+
+// ------------------------------------------------------------
+// Expiry checks
+// ------------------------------------------------------------
+
+// CheckExpiring alerts once per threshold for secrets that expire within
+// one of the thresholds, and once more when they actually expire.
+// It returns the number of secrets inspected.
+func (s *NotificationService) CheckExpiring(ctx context.Context, thresholds []time.Duration) (int, error) {
+	if len(thresholds) == 0 {
+		return 0, nil
+	}
+	sorted := append([]time.Duration(nil), thresholds...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	now := time.Now()
+	secrets, err := s.secretRepo.GetSecretsExpiringBetween(ctx, now.Add(-expiredNotificationWindow), now.Add(sorted[len(sorted)-1]))
+	if err != nil {
+		return 0, err
+	}
+
+	for _, secret := range secrets {
+		expiresAt := *secret.ExpiresAt
+		remaining := expiresAt.Sub(now)
+		data := map[string]any{
+			"name":       secret.Name,
+			"version":    secret.Version,
+			"expires_at": expiresAt.UTC(),
+		}
+
+		if remaining <= 0 {
+			key := fmt.Sprintf("expired:%s:%d", secret.ID, expiresAt.Unix())
+			s.NotifyOnce(ctx, secret.ProjectID, &secret.ID, NotifySecretExpired, key, data)
+			continue
+		}
+
+		// only the tightest threshold that was crossed fires; wider ones were
+		// either sent on an earlier run or are no longer relevant
+		for _, threshold := range sorted {
+			if remaining > threshold {
+				continue
+			}
+			data["threshold"] = threshold.String()
+			key := fmt.Sprintf("expiring:%s:%d:%s", secret.ID, expiresAt.Unix(), threshold)
+			s.NotifyOnce(ctx, secret.ProjectID, &secret.ID, NotifySecretExpiring, key, data)
+			break
+		}
+	}
+
+	return len(secrets), nil
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/google/uuid"
+)
+
+// org and team slugs must match what the gateway sends
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type OrgService struct {
+	repo         *repository.OrgRepository
+	projectRepo  *repository.ProjectRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+}
+
+func NewOrgService(repo *repository.OrgRepository, projectRepo *repository.ProjectRepository, authorizer *Authorizer, auditService *AuditService) *OrgService {
+	return &OrgService{
+		repo:         repo,
+		projectRepo:  projectRepo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+	}
+}
+
+// CreateOrg registers an organization the gateway already knows. Its admins
+// (or service administrators) can register it.
+func (s *OrgService) CreateOrg(ctx context.Context, userID string, slug string, name string) (*models.Organization, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if !slugPattern.MatchString(slug) {
+		return nil, errors.New("slug must be lowercase letters, digits, - or _")
+	}
+	if strings.TrimSpace(name) == "" {
+		name = slug
+	}
+	if !containsString(subjectFor(ctx, userID).OrgAdmins, slug) && !identity.IsAdmin(userID) {
+		return nil, errors.New("forbidden: only admins of " + slug + " can register it")
+	}
+
+	org := &models.Organization{
+		Slug:      slug,
+		Name:      name,
+		CreatedBy: userUUID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.CreateOrg(ctx, org); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("organization " + slug + " already exists")
+		}
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"CREATE_ORG",
+		"Organization "+slug+" created",
+	)
+	return org, nil
+}
+
+// ListOrgs lists the registered organizations the user belongs to
+func (s *OrgService) ListOrgs(ctx context.Context, userID string) ([]models.Organization, error) {
+	subject := subjectFor(ctx, userID)
+	slugs := append([]string{}, subject.Orgs...)
+	return s.repo.GetOrgsBySlugs(ctx, append(slugs, subject.OrgAdmins...))
+}
+
+func (s *OrgService) GetOrg(ctx context.Context, userID string, orgID string) (*models.Organization, error) {
+	return s.org(ctx, userID, orgID, false)
+}
+
+// ListProjects lists every live project of the organization, for its admins
+func (s *OrgService) ListProjects(ctx context.Context, userID string, orgID string) ([]models.Project, error) {
+	org, err := s.org(ctx, userID, orgID, true)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.projectRepo.GetProjectsByOrg(ctx, org.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	return TokenProjects(ctx, userID, projects), nil
+}
+
+func (s *OrgService) CreateTeam(ctx context.Context, userID string, orgID string, slug string, name string) (*models.Team, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if !slugPattern.MatchString(slug) {
+		return nil, errors.New("slug must be lowercase letters, digits, - or _")
+	}
+	if strings.TrimSpace(name) == "" {
+		name = slug
+	}
+	org, err := s.org(ctx, userID, orgID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &models.Team{
+		OrgID:     org.ID,
+		Slug:      slug,
+		Name:      name,
+		CreatedBy: userUUID,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.repo.CreateTeam(ctx, team); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("team " + org.Slug + "/" + slug + " already exists")
+		}
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"CREATE_TEAM",
+		"Team "+org.Slug+"/"+slug+" created",
+	)
+	return team, nil
+}
+
+func (s *OrgService) ListTeams(ctx context.Context, userID string, orgID string) ([]models.Team, error) {
+	org, err := s.org(ctx, userID, orgID, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetTeamsByOrg(ctx, org.ID.String())
+}
+
+// DeleteTeam deletes a team that no longer owns projects
+func (s *OrgService) DeleteTeam(ctx context.Context, userID string, orgID string, teamID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	org, err := s.org(ctx, userID, orgID, true)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(teamID); err != nil {
+		return errors.New("team not found")
+	}
+	team, err := s.repo.GetTeamByID(ctx, teamID)
+	if err != nil || team == nil || team.OrgID != org.ID {
+		return errors.New("team not found")
+	}
+
+	owned, err := s.projectRepo.CountProjectsByOwner(ctx, OwnerTeam, teamID)
+	if err != nil {
+		return err
+	}
+	if owned > 0 {
+		return errors.New("team still owns projects, transfer them first (including the ones in trash)")
+	}
+
+	if err := s.repo.DeleteTeam(ctx, teamID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"DELETE_TEAM",
+		"Team "+org.Slug+"/"+team.Slug+" deleted",
+	)
+	return nil
+}
+
+// org loads an organization the user belongs to, or administers when admin is set
+func (s *OrgService) org(ctx context.Context, userID string, orgID string, admin bool) (*models.Organization, error) {
+	if _, err := uuid.Parse(orgID); err != nil {
+		return nil, errors.New("organization not found")
+	}
+	org, err := s.repo.GetOrgByID(ctx, orgID)
+	if err != nil || org == nil {
+		return nil, errors.New("organization not found")
+	}
+
+	subject := subjectFor(ctx, userID)
+	isAdmin := containsString(subject.OrgAdmins, org.Slug) || identity.IsAdmin(userID)
+	if isAdmin {
+		return org, nil
+	}
+	if !containsString(subject.Orgs, org.Slug) {
+		return nil, errors.New("organization not found")
+	}
+	if admin {
+		return nil, errors.New("forbidden: only admins of " + org.Slug + " can do this")
+	}
+	return org, nil
+}
@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+type PolicyService struct {
+	repo         *repository.PolicyRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+}
+
+func NewPolicyService(repo *repository.PolicyRepository, authorizer *Authorizer, auditService *AuditService) *PolicyService {
+	return &PolicyService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+	}
+}
+
+// PolicyInput describes a new policy or the new contents of one
+type PolicyInput struct {
+	Name        string
+	Description *string
+	Rules       []models.PolicyRule
+}
+
+// EvaluateInput describes the request to explain. An empty UserID means the caller.
+type EvaluateInput struct {
+	UserID     string
+	Groups     []string
+	ProjectID  string
+	SecretName string
+	Permission Permission
+}
+
+func (s *PolicyService) CreatePolicy(ctx context.Context, userID string, input PolicyInput) (*models.Policy, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if err := requireAdmin(userID); err != nil {
+		return nil, err
+	}
+	if err := validatePolicy(input); err != nil {
+		return nil, err
+	}
+
+	policy := &models.Policy{
+		Name:        input.Name,
+		Description: input.Description,
+		Rules:       input.Rules,
+		CreatedBy:   userUUID,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := s.repo.CreatePolicy(ctx, policy); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("a policy named " + input.Name + " already exists")
+		}
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"CREATE_POLICY",
+		"Policy "+policy.Name+" created",
+	)
+	return policy, nil
+}
+
+func (s *PolicyService) ListPolicies(ctx context.Context, userID string) ([]models.Policy, error) {
+	if err := requireAdmin(userID); err != nil {
+		return nil, err
+	}
+	return s.repo.GetPolicies(ctx)
+}
+
+// GetPolicy returns a policy together with who it is attached to
+func (s *PolicyService) GetPolicy(ctx context.Context, userID string, policyID string) (*models.Policy, []models.PolicyAttachment, error) {
+	if err := requireAdmin(userID); err != nil {
+		return nil, nil, err
+	}
+	policy, err := s.policy(ctx, policyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	attachments, err := s.repo.GetAttachmentsByPolicy(ctx, policyID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return policy, attachments, nil
+}
+
+func (s *PolicyService) UpdatePolicy(ctx context.Context, userID string, policyID string, input PolicyInput) (*models.Policy, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if err := requireAdmin(userID); err != nil {
+		return nil, err
+	}
+	if err := validatePolicy(input); err != nil {
+		return nil, err
+	}
+	policy, err := s.policy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	policy.Name = input.Name
+	policy.Description = input.Description
+	policy.Rules = input.Rules
+	policy.UpdatedAt = time.Now()
+	if err := s.repo.UpdatePolicy(ctx, policy); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("a policy named " + input.Name + " already exists")
+		}
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"UPDATE_POLICY",
+		"Policy "+policy.Name+" updated",
+	)
+	return policy, nil
+}
+
+func (s *PolicyService) DeletePolicy(ctx context.Context, userID string, policyID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	if err := requireAdmin(userID); err != nil {
+		return err
+	}
+	policy, err := s.policy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeletePolicy(ctx, policyID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"DELETE_POLICY",
+		"Policy "+policy.Name+" deleted with its attachments",
+	)
+	return nil
+}
+
+// AttachPolicy attaches a policy to a user, a gateway group, a service account
+// or a client certificate name
+func (s *PolicyService) AttachPolicy(ctx context.Context, userID string, policyID string, principalType string, principalID string) (*models.PolicyAttachment, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if err := requireAdmin(userID); err != nil {
+		return nil, err
+	}
+	switch principalType {
+	case PrincipalUser, PrincipalServiceAccount:
+		if _, err := uuid.Parse(principalID); err != nil {
+			return nil, errors.New("principal_id must be a uuid for " + principalType)
+		}
+	case PrincipalGroup, PrincipalCertificate:
+		if strings.TrimSpace(principalID) == "" {
+			return nil, errors.New("principal_id is required")
+		}
+	default:
+		return nil, errors.New("principal_type must be user, group, service_account or certificate")
+	}
+
+	policy, err := s.policy(ctx, policyID)
+	if err != nil {
+		return nil, err
+	}
+
+	attachment := &models.PolicyAttachment{
+		PolicyID:      policy.ID,
+		PrincipalType: principalType,
+		PrincipalID:   principalID,
+		CreatedBy:     userUUID,
+		CreatedAt:     time.Now(),
+	}
+	attached, err := s.repo.CreateAttachment(ctx, attachment)
+	if err != nil {
+		return nil, err
+	}
+	if !attached {
+		return nil, errors.New("policy is already attached to " + principalType + " " + principalID)
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"ATTACH_POLICY",
+		"Policy "+policy.Name+" attached to "+principalType+" "+principalID,
+	)
+	return attachment, nil
+}
+
+func (s *PolicyService) DetachPolicy(ctx context.Context, userID string, policyID string, attachmentID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	if err := requireAdmin(userID); err != nil {
+		return err
+	}
+	policy, err := s.policy(ctx, policyID)
+	if err != nil {
+		return err
+	}
+	detached, err := s.repo.DeleteAttachment(ctx, policyID, attachmentID)
+	if err != nil {
+		return err
+	}
+	if !detached {
+		return errors.New("attachment not found")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		nil,
+		nil,
+		"DETACH_POLICY",
+		"Policy "+policy.Name+" detached ("+attachmentID+")",
+	)
+	return nil
+}
+
+// Evaluate explains whether a request would be allowed. Users can evaluate
+// their own access; evaluating another user or a set of groups takes an
+// administrator.
+func (s *PolicyService) Evaluate(ctx context.Context, userID string, input EvaluateInput) (*Decision, error) {
+	if !ValidPermission(input.Permission) {
+		return nil, errors.New("invalid capability")
+	}
+
+	subject := subjectFor(ctx, userID)
+	if (input.UserID != "" && input.UserID != userID) || input.Groups != nil {
+		// explaining someone else's access, or groups the caller may not be in,
+		// would leak what others can do
+		if err := requireAdmin(userID); err != nil {
+			return nil, err
+		}
+		if input.UserID != "" {
+			if _, err := uuid.Parse(input.UserID); err != nil {
+				return nil, errors.New("invalid user_id")
+			}
+			subject = Subject{UserID: input.UserID}
+		}
+		subject.Groups = input.Groups
+	}
+
+	project, err := s.Authorizer.Project(ctx, input.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Authorizer.Decide(ctx, project, subject, input.SecretName, input.Permission)
+}
+
+func (s *PolicyService) policy(ctx context.Context, policyID string) (*models.Policy, error) {
+	if _, err := uuid.Parse(policyID); err != nil {
+		return nil, errors.New("policy not found")
+	}
+	policy, err := s.repo.GetPolicyByID(ctx, policyID)
+	if err != nil || policy == nil {
+		return nil, errors.New("policy not found")
+	}
+	return policy, nil
+}
+
+// requireAdmin makes sure the user is a service administrator
+func requireAdmin(userID string) error {
+	if !identity.IsAdmin(userID) {
+		return errors.New("forbidden: only administrators can manage policies")
+	}
+	return nil
+}
+
+func validatePolicy(input PolicyInput) error {
+	if strings.TrimSpace(input.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(input.Rules) == 0 {
+		return errors.New("a policy needs at least one rule")
+	}
+	for _, rule := range input.Rules {
+		if err := utils.ValidatePathGlob(rule.Path); err != nil {
+			return errors.New("rule " + rule.Path + ": " + err.Error())
+		}
+		if len(rule.Capabilities) == 0 {
+			return errors.New("rule " + rule.Path + ": capabilities are required")
+		}
+		for _, capability := range rule.Capabilities {
+			if !containsString(policyCapabilities, capability) {
+				return errors.New("rule " + rule.Path + ": unknown capability " + capability)
+			}
+		}
+	}
+	return nil
+}
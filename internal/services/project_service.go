@@ -3,33 +3,63 @@ package services
 import (
 	"context"
 	"errors"
+	"strconv"
+	"time"
 
 	"github.com/akansha204/cryptex-secretservice/internal/models"
 	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
 	"github.com/google/uuid"
 )
 
 type ProjectService struct {
-	repo         *repository.ProjectRepository
-	AuditService *AuditService
+	repo           *repository.ProjectRepository
+	Authorizer     *Authorizer
+	AuditService   *AuditService
+	WebhookService *WebhookService
 }
 
-func NewProjectService(repo *repository.ProjectRepository, auditService *AuditService) *ProjectService {
+func NewProjectService(repo *repository.ProjectRepository, authorizer *Authorizer, auditService *AuditService, webhookService *WebhookService) *ProjectService {
 	return &ProjectService{
-		repo:         repo,
-		AuditService: auditService,
+		repo:           repo,
+		Authorizer:     authorizer,
+		AuditService:   auditService,
+		WebhookService: webhookService,
 	}
 }
 
-func (s *ProjectService) CreateProject(ctx context.Context, userID string, name string, description *string) (*models.Project, error) {
+// ProjectOwner names who owns a project: a user, team or org and its ID
+type ProjectOwner struct {
+	Type string
+	ID   string
+}
+
+func (s *ProjectService) CreateProject(ctx context.Context, userID string, name string, description *string, minTTL *time.Duration, maxTTL *time.Duration, requireApproval *bool, allowedCIDRs *[]string, owner ProjectOwner) (*models.Project, error) {
 
 	userUUID := uuid.MustParse(userID)
 
 	project := &models.Project{
 		UserID:      userUUID,
+		OwnerType:   OwnerUser,
 		Name:        name,
 		Description: description,
 	}
+	if requireApproval != nil {
+		project.RequireApproval = *requireApproval
+	}
+	if err := applyTTLBounds(project, minTTL, maxTTL); err != nil {
+		return nil, err
+	}
+	if err := applyProjectCIDRs(project, allowedCIDRs); err != nil {
+		return nil, err
+	}
+	if owner.Type != "" && owner.Type != OwnerUser {
+		// the creator stays in UserID, the team or org owns the project
+		if err := s.Authorizer.PlaceProject(ctx, userID, project, owner.Type, owner.ID); err != nil {
+			return nil, err
+		}
+		project.UserID = userUUID
+	}
 
 	err := s.repo.CreateProject(ctx, project)
 	if err != nil {
@@ -44,40 +74,55 @@ func (s *ProjectService) CreateProject(ctx context.Context, userID string, name
 		"CREATE_PROJECT",
 		"Project created successfully",
 	)
+	s.WebhookService.Emit(ctx, project.ID, nil, &userUUID, EventProjectCreated, map[string]any{
+		"name": project.Name,
+	})
 
 	return project, nil
 }
-func (s *ProjectService) GetProjectByID(ctx context.Context, projectID string) (*models.Project, error) {
-	project, err := s.repo.GetProjectByID(ctx, projectID)
+func (s *ProjectService) GetProjectByID(ctx context.Context, projectID string, userID string) (*models.Project, error) {
+	return s.Authorizer.Authorize(ctx, userID, projectID, PermList)
+}
+
+// GetProjectsByUser lists the projects the user owns, directly or through a
+// team or org, and the ones they are a member of
+func (s *ProjectService) GetProjectsByUser(ctx context.Context, userID string) ([]models.Project, error) {
+	ownership, err := s.Authorizer.Ownership(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
-	if project == nil || project.DeletedAt != nil {
-		return nil, errors.New("project not found")
+	projects, err := s.repo.GetProjectsByUserID(ctx, ownership)
+	if err != nil {
+		return nil, err
 	}
-	return project, nil
-
-}
-func (s *ProjectService) GetProjectsByUser(ctx context.Context, userID string) ([]models.Project, error) {
-	return s.repo.GetProjectsByUserID(ctx, userID)
+	return TokenProjects(ctx, userID, projects), nil
 }
 
-func (s *ProjectService) UpdateProject(ctx context.Context, projectID string, userID string, name string, description *string) (*models.Project, error) {
+func (s *ProjectService) UpdateProject(ctx context.Context, projectID string, userID string, name string, description *string, minTTL *time.Duration, maxTTL *time.Duration, requireApproval *bool, allowedCIDRs *[]string) (*models.Project, error) {
 	userUUID := uuid.MustParse(userID)
 
-	project, err := s.repo.GetProjectByID(ctx, projectID)
-	if err != nil || project == nil {
-		return nil, errors.New("project not found")
-	}
-	if project.DeletedAt != nil {
-		return nil, errors.New("project not found")
-	}
-	if project.UserID.String() != userID {
-		return nil, errors.New("forbidden: not your project")
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
 	}
 
 	project.Name = name
 	project.Description = description
+	if err := applyTTLBounds(project, minTTL, maxTTL); err != nil {
+		return nil, err
+	}
+	if requireApproval != nil && *requireApproval != project.RequireApproval {
+		if !*requireApproval {
+			// turning the safeguard off is for owners only
+			if err := s.Authorizer.Check(ctx, project, userID, PermOwn); err != nil {
+				return nil, err
+			}
+		}
+		project.RequireApproval = *requireApproval
+	}
+	if err := applyProjectCIDRs(project, allowedCIDRs); err != nil {
+		return nil, err
+	}
 
 	err = s.repo.UpdateProject(ctx, project)
 	if err != nil {
@@ -92,31 +137,253 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID string, us
 		"UPDATE_PROJECT",
 		"Project details updated",
 	)
+	s.WebhookService.Emit(ctx, project.ID, nil, &userUUID, EventProjectUpdated, map[string]any{
+		"name": project.Name,
+	})
 	return project, nil
 }
 
 func (s *ProjectService) DeleteProject(ctx context.Context, projectID string, userID string) error {
 	userUUID := uuid.MustParse(userID)
 
-	project, err := s.repo.GetProjectByID(ctx, projectID)
-	if err != nil || project == nil {
-		return errors.New("project not found")
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermOwn)
+	if err != nil {
+		return err
+	}
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"DELETE_PROJECT",
+		"Project deleted successfully",
+	)
+	// queued before the soft delete so subscribers of the project still receive it
+	s.WebhookService.Emit(ctx, project.ID, nil, &userUUID, EventProjectDeleted, map[string]any{
+		"name": project.Name,
+	})
+
+	secrets, err := s.repo.SoftDeleteProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	s.auditSecrets(ctx, &userUUID, secrets, "DELETE_SECRET", "Secret deleted with its project")
+
+	return nil
+}
+
+// TransferProject hands a project to another user, a team or an org. Only
+// owners can transfer, and they may lose their ownership by doing so.
+func (s *ProjectService) TransferProject(ctx context.Context, projectID string, userID string, owner ProjectOwner) (*models.Project, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermOwn)
+	if err != nil {
+		return nil, err
+	}
+	from := describeOwner(project)
+
+	if err := s.Authorizer.PlaceProject(ctx, userID, project, owner.Type, owner.ID); err != nil {
+		return nil, err
 	}
-	if project.DeletedAt != nil {
-		return errors.New("project already deleted")
+	to := describeOwner(project)
+	if from == to {
+		return nil, errors.New("project is already owned by " + to)
 	}
+	project.UpdatedAt = time.Now()
 
-	if project.UserID.String() != userID {
-		return errors.New("forbidden: not your project")
+	if err := s.repo.TransferProject(ctx, project); err != nil {
+		return nil, err
 	}
+
 	s.AuditService.Log(
 		ctx,
 		&userUUID,
 		&project.ID,
 		nil,
-		"DELETE_PROJECT",
-		"Project deleted successfully",
+		"TRANSFER_PROJECT",
+		"Project transferred from "+from+" to "+to,
+	)
+	s.WebhookService.Emit(ctx, project.ID, nil, &userUUID, EventProjectTransferred, map[string]any{
+		"name": project.Name,
+		"from": from,
+		"to":   to,
+	})
+	return project, nil
+}
+
+// describeOwner names the owner of a project for audit records, e.g. "team 3f8c..."
+func describeOwner(project *models.Project) string {
+	if owner := OwnerUserID(project); owner != "" {
+		return OwnerUser + " " + owner
+	}
+	return project.OwnerType + " " + project.OwnerID.String()
+}
+
+// TrashedProject is a soft-deleted project with the time the purge job removes it
+type TrashedProject struct {
+	models.Project
+	PurgeAt time.Time
+}
+
+func (s *ProjectService) ListTrash(ctx context.Context, userID string) ([]TrashedProject, error) {
+	ownership, err := s.Authorizer.Ownership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := s.repo.GetDeletedProjectsByUserID(ctx, ownership)
+	if err != nil {
+		return nil, err
+	}
+	projects = TokenProjects(ctx, userID, projects)
+
+	retention := utils.PurgeRetention()
+	trashed := make([]TrashedProject, 0, len(projects))
+	for _, project := range projects {
+		trashed = append(trashed, TrashedProject{Project: project, PurgeAt: project.DeletedAt.Add(retention)})
+	}
+	return trashed, nil
+}
+
+func (s *ProjectService) RestoreProject(ctx context.Context, projectID string, userID string) (*models.Project, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.trashedProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// names only clash within the namespace of the current owner
+	ownerType, ownerID := OwnerUser, OwnerUserID(project)
+	if ownerID == "" {
+		ownerType, ownerID = project.OwnerType, project.OwnerID.String()
+	}
+	conflict, err := s.repo.GetProjectByName(ctx, ownerType, ownerID, project.Name)
+	if err != nil {
+		return nil, err
+	}
+	if conflict != nil {
+		return nil, errors.New("a project named " + project.Name + " already exists, rename it before restoring")
+	}
+
+	secrets, err := s.repo.RestoreProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	project.DeletedAt = nil
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"RESTORE_PROJECT",
+		"Project restored from trash with "+strconv.Itoa(len(secrets))+" secret(s)",
+	)
+	s.auditSecrets(ctx, &userUUID, secrets, "RESTORE_SECRET", "Secret restored with its project")
+	return project, nil
+}
+
+// PurgeProject permanently deletes a project from the trash without waiting for the purge job
+func (s *ProjectService) PurgeProject(ctx context.Context, projectID string, userID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.trashedProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	secrets, err := s.repo.PurgeProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"PURGE_PROJECT",
+		"Project permanently deleted from trash with "+strconv.Itoa(len(secrets))+" secret version(s)",
 	)
+	s.auditSecrets(ctx, &userUUID, secrets, "PURGE_SECRET", "Secret permanently deleted with its project")
+	return nil
+}
+
+// auditSecrets writes one audit record per secret touched by a project-wide change
+func (s *ProjectService) auditSecrets(ctx context.Context, userID *uuid.UUID, secrets []models.Secret, action string, message string) {
+	for _, secret := range secrets {
+		s.AuditService.Log(
+			ctx,
+			userID,
+			&secret.ProjectID,
+			&secret.ID,
+			action,
+			message+" (version "+strconv.Itoa(secret.Version)+")",
+		)
+	}
+}
+
+// trashedProject loads a soft-deleted project the user is an owner of
+func (s *ProjectService) trashedProject(ctx context.Context, projectID string, userID string) (*models.Project, error) {
+	project, err := s.repo.GetDeletedProjectByID(ctx, projectID)
+	if err != nil || project == nil {
+		return nil, errors.New("project not found in trash")
+	}
+	if err := s.Authorizer.Check(ctx, project, userID, PermOwn); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+// applyProjectCIDRs sets or (with an empty list) clears the networks secrets of
+// the project may be read from
+func applyProjectCIDRs(project *models.Project, allowedCIDRs *[]string) error {
+	if allowedCIDRs == nil {
+		return nil
+	}
+	cidrs, err := utils.NormalizeCIDRs(*allowedCIDRs)
+	if err != nil {
+		return err
+	}
+	project.AllowedCIDRs = cidrs
+	return nil
+}
 
-	return s.repo.SoftDeleteProject(ctx, projectID)
+// applyTTLBounds sets the secret lifetime bounds of a project; nil leaves a
+// bound unchanged and zero removes it
+func applyTTLBounds(project *models.Project, minTTL *time.Duration, maxTTL *time.Duration) error {
+	if minTTL != nil {
+		bound, err := ttlBound("min_ttl", *minTTL)
+		if err != nil {
+			return err
+		}
+		project.MinTTL = bound
+	}
+	if maxTTL != nil {
+		bound, err := ttlBound("max_ttl", *maxTTL)
+		if err != nil {
+			return err
+		}
+		project.MaxTTL = bound
+	}
+	if project.MinTTL != nil && project.MaxTTL != nil && *project.MinTTL > *project.MaxTTL {
+		return errors.New("min_ttl cannot be greater than max_ttl")
+	}
+	return nil
+}
+
+// ttlBound converts a bound to seconds, or nil for zero
+func ttlBound(field string, d time.Duration) (*int64, error) {
+	if d < 0 {
+		return nil, errors.New(field + " cannot be negative")
+	}
+	if d == 0 {
+		return nil, nil
+	}
+	seconds := int64(d / time.Second)
+	if seconds < 1 {
+		return nil, errors.New(field + " must be at least one second")
+	}
+	return &seconds, nil
 }
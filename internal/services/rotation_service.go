@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	rotationRetryBase = time.Minute
+	rotationRetryMax  = time.Hour
+
+	// how long a claimed policy stays hidden from other scheduler runs
+	rotationClaimTimeout = 5 * time.Minute
+	rotationBatchSize    = 50
+)
+
+type RotationService struct {
+	rotationRepo        *repository.RotationRepository
+	secretRepo          *repository.SecretRepository
+	Authorizer          *Authorizer
+	AuditService        *AuditService
+	NotificationService *NotificationService
+	WebhookService      *WebhookService
+	rotators            map[string]Rotator
+}
+
+func NewRotationService(rotationRepo *repository.RotationRepository, secretRepo *repository.SecretRepository, authorizer *Authorizer, auditService *AuditService, notificationService *NotificationService, webhookService *WebhookService) *RotationService {
+	return &RotationService{
+		rotationRepo:        rotationRepo,
+		secretRepo:          secretRepo,
+		Authorizer:          authorizer,
+		AuditService:        auditService,
+		NotificationService: notificationService,
+		WebhookService:      webhookService,
+		rotators: map[string]Rotator{
+			RotatorGenerator: GeneratorRotator{},
+			RotatorWebhook:   NewWebhookRotator(),
+		},
+	}
+}
+
+// RegisterRotator makes an additional rotator available to rotation policies
+func (s *RotationService) RegisterRotator(name string, rotator Rotator) {
+	s.rotators[name] = rotator
+}
+
+type RotationPolicyInput struct {
+	IntervalHours *int
+	CronExpr      *string
+	Rotator       string
+	Generate      *utils.GeneratePolicy
+	WebhookURL    *string
+	WebhookSecret *string
+	Enabled       *bool
+}
+
+func (s *RotationService) SetPolicy(
+	ctx context.Context,
+	userID string,
+	projectID string,
+	secretID string,
+	input RotationPolicyInput,
+) (*models.RotationPolicy, error) {
+
+	userUUID := uuid.MustParse(userID)
+	project, secret, err := s.Authorizer.AuthorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, PermUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkManualRotation(project); err != nil {
+		return nil, err
+	}
+
+	if input.Rotator == "" {
+		input.Rotator = RotatorGenerator
+	}
+	if _, ok := s.rotators[input.Rotator]; !ok {
+		return nil, errors.New("unknown rotator: " + input.Rotator)
+	}
+	if input.Rotator == RotatorWebhook {
+		if input.WebhookURL == nil {
+			return nil, errors.New("webhook_url is required for the webhook rotator")
+		}
+		if err := ValidateRotationWebhookURL(*input.WebhookURL); err != nil {
+			return nil, err
+		}
+	}
+	if input.Generate != nil {
+		// fail early on a policy the generator would reject at rotation time
+		if _, err := utils.GenerateSecret(*input.Generate); err != nil {
+			return nil, err
+		}
+	}
+
+	policy := &models.RotationPolicy{
+		ProjectID:     secret.ProjectID,
+		SecretID:      secret.ID,
+		IntervalHours: input.IntervalHours,
+		CronExpr:      input.CronExpr,
+		Rotator:       input.Rotator,
+		Generate:      input.Generate,
+		WebhookURL:    input.WebhookURL,
+		Enabled:       input.Enabled == nil || *input.Enabled,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	if input.WebhookSecret != nil {
+		encrypted, err := utils.Encrypt(*input.WebhookSecret)
+		if err != nil {
+			return nil, err
+		}
+		policy.WebhookSecret = &encrypted
+	}
+
+	next, err := nextRotation(policy, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	policy.NextRunAt = next
+
+	if err := s.rotationRepo.UpsertPolicy(ctx, policy); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"SET_ROTATION_POLICY",
+		"Rotation policy set ("+policy.Rotator+"), next run at "+next.Format(time.RFC3339),
+	)
+
+	return policy, nil
+}
+
+func (s *RotationService) GetPolicy(ctx context.Context, userID string, projectID string, secretID string) (*models.RotationPolicy, error) {
+	_, policy, err := s.secretPolicy(ctx, userID, projectID, secretID, PermList)
+	return policy, err
+}
+
+// secretPolicy loads the rotation policy of a secret the user has perm on,
+// with the project of the secret
+func (s *RotationService) secretPolicy(ctx context.Context, userID string, projectID string, secretID string, perm Permission) (*models.Project, *models.RotationPolicy, error) {
+	project, secret, err := s.Authorizer.AuthorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, perm)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policy, err := s.rotationRepo.GetPolicyBySecretID(ctx, secret.ID.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	if policy == nil {
+		return nil, nil, errors.New("rotation policy not found")
+	}
+	return project, policy, nil
+}
+
+// checkManualRotation refuses to set up or trigger rotation by hand in a
+// project that requires approval, since either one changes the secret value
+// without a change request. Policies set before approval was turned on keep
+// running on their schedule.
+func checkManualRotation(project *models.Project) error {
+	if project.RequireApproval {
+		return errors.New("forbidden: rotation cannot be set up or run by hand in a project that requires approval")
+	}
+	return nil
+}
+
+func (s *RotationService) DeletePolicy(ctx context.Context, userID string, projectID string, secretID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	_, secret, err := s.Authorizer.AuthorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, PermUpdate)
+	if err != nil {
+		return err
+	}
+
+	if err := s.rotationRepo.DeletePolicy(ctx, secret.ID.String()); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"DELETE_ROTATION_POLICY",
+		"Rotation policy removed",
+	)
+	return nil
+}
+
+// RotateNow runs the rotation policy of a secret immediately
+func (s *RotationService) RotateNow(ctx context.Context, userID string, projectID string, secretID string) (*models.Secret, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, policy, err := s.secretPolicy(ctx, userID, projectID, secretID, PermUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkManualRotation(project); err != nil {
+		return nil, err
+	}
+	return s.rotate(ctx, policy, &userUUID)
+}
+
+// RunDue rotates every secret whose policy is due and returns how many
+// rotations were attempted. It is driven by the scheduler in main.
+func (s *RotationService) RunDue(ctx context.Context) (int, error) {
+	policies, err := s.rotationRepo.ClaimDuePolicies(ctx, time.Now(), rotationClaimTimeout, rotationBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range policies {
+		if _, err := s.rotate(ctx, &policies[i], nil); err != nil {
+			log.Printf("[ROTATION] secret=%s failed: %v", policies[i].SecretID, err)
+		}
+	}
+	return len(policies), nil
+}
+
+func (s *RotationService) rotate(ctx context.Context, policy *models.RotationPolicy, userID *uuid.UUID) (*models.Secret, error) {
+	secret, err := s.secretRepo.GetSecretByID(ctx, policy.SecretID.String())
+	if err != nil {
+		return nil, s.recordFailure(ctx, policy, userID, err)
+	}
+	if secret == nil || secret.DeletedAt != nil || secret.Revoked {
+		// nothing left to rotate, stop scheduling this policy
+		policy.Enabled = false
+		return nil, s.recordFailure(ctx, policy, userID, errors.New("secret is deleted or revoked"))
+	}
+
+	rotator, ok := s.rotators[policy.Rotator]
+	if !ok {
+		return nil, s.recordFailure(ctx, policy, userID, errors.New("unknown rotator: "+policy.Rotator))
+	}
+
+	value, err := rotator.Rotate(ctx, policy, secret)
+	if err != nil {
+		return nil, s.recordFailure(ctx, policy, userID, err)
+	}
+
+	encrypted, err := utils.Encrypt(value)
+	if err != nil {
+		return nil, s.recordFailure(ctx, policy, userID, err)
+	}
+
+	now := time.Now()
+	secret.Value = encrypted
+	secret.Version += 1
+	secret.UpdatedAt = now
+	if secret.TTL != nil {
+		expires := now.Add(time.Duration(*secret.TTL) * time.Second)
+		secret.ExpiresAt = &expires
+	}
+
+	if err := s.secretRepo.UpdateSecret(ctx, secret); err != nil {
+		return nil, s.recordFailure(ctx, policy, userID, err)
+	}
+
+	next, err := nextRotation(policy, now)
+	if err != nil {
+		policy.Enabled = false
+		next = now
+	}
+	policy.NextRunAt = next
+	policy.LastRunAt = &now
+	policy.LastError = nil
+	policy.FailureCount = 0
+	policy.UpdatedAt = now
+
+	if err := s.rotationRepo.UpdatePolicy(ctx, policy); err != nil {
+		log.Printf("[ROTATION] cannot update policy %s: %v", policy.ID, err)
+	}
+
+	s.AuditService.Log(
+		ctx,
+		userID,
+		&secret.ProjectID,
+		&secret.ID,
+		"ROTATE_SECRET",
+		"Secret rotated by "+policy.Rotator+" (version "+strconv.Itoa(secret.Version)+")",
+	)
+	s.WebhookService.Emit(ctx, secret.ProjectID, &secret.ID, userID, EventSecretRotated, map[string]any{
+		"name":    secret.Name,
+		"version": secret.Version,
+		"rotator": policy.Rotator,
+	})
+
+	return secret, nil
+}
+
+// recordFailure audits a failed rotation and schedules a retry with exponential backoff
+func (s *RotationService) recordFailure(ctx context.Context, policy *models.RotationPolicy, userID *uuid.UUID, cause error) error {
+	now := time.Now()
+	message := cause.Error()
+
+	policy.FailureCount += 1
+	policy.LastRunAt = &now
+	policy.LastError = &message
+	policy.NextRunAt = now.Add(rotationBackoff(policy.FailureCount))
+	policy.UpdatedAt = now
+
+	if err := s.rotationRepo.UpdatePolicy(ctx, policy); err != nil {
+		log.Printf("[ROTATION] cannot update policy %s: %v", policy.ID, err)
+	}
+
+	s.AuditService.Log(
+		ctx,
+		userID,
+		&policy.ProjectID,
+		&policy.SecretID,
+		"ROTATION_FAILED",
+		fmt.Sprintf("Rotation attempt %d failed: %s", policy.FailureCount, message),
+	)
+
+	// alert when a failure streak starts and when the policy gives up,
+	// not on every retry
+	if policy.FailureCount == 1 || !policy.Enabled {
+		s.NotificationService.Notify(ctx, policy.ProjectID, &policy.SecretID, NotifyRotationFailed, map[string]any{
+			"rotator":       policy.Rotator,
+			"error":         message,
+			"attempt":       policy.FailureCount,
+			"next_retry_at": policy.NextRunAt.UTC(),
+			"disabled":      !policy.Enabled,
+		})
+	}
+
+	return cause
+}
+
+func rotationBackoff(failures int) time.Duration {
+	backoff := rotationRetryBase
+	for i := 1; i < failures && backoff < rotationRetryMax; i++ {
+		backoff *= 2
+	}
+	if backoff > rotationRetryMax {
+		backoff = rotationRetryMax
+	}
+	return backoff
+}
+
+// nextRotation computes the next scheduled run of a policy after from
+func nextRotation(policy *models.RotationPolicy, from time.Time) (time.Time, error) {
+	switch {
+	case policy.IntervalHours != nil && policy.CronExpr != nil:
+		return time.Time{}, errors.New("use either interval_hours or cron, not both")
+	case policy.IntervalHours != nil:
+		if *policy.IntervalHours < 1 {
+			return time.Time{}, errors.New("interval_hours must be at least 1")
+		}
+		return from.Add(time.Duration(*policy.IntervalHours) * time.Hour), nil
+	case policy.CronExpr != nil:
+		schedule, err := utils.ParseCron(*policy.CronExpr)
+		if err != nil {
+			return time.Time{}, err
+		}
+		next := schedule.Next(from)
+		if next.IsZero() {
+			return time.Time{}, errors.New("cron expression never fires")
+		}
+		return next, nil
+	default:
+		return time.Time{}, errors.New("interval_hours or cron is required")
+	}
+}
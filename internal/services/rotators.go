@@ -0,0 +1,139 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+)
+
+const (
+	RotatorGenerator = "generator"
+	RotatorWebhook   = "webhook"
+)
+
+// Rotator produces the next value of a secret for a rotation policy
+type Rotator interface {
+	Rotate(ctx context.Context, policy *models.RotationPolicy, secret *models.Secret) (string, error)
+}
+
+// GeneratorRotator mints the new value with the built-in generator
+type GeneratorRotator struct{}
+
+func (GeneratorRotator) Rotate(ctx context.Context, policy *models.RotationPolicy, secret *models.Secret) (string, error) {
+	generate := utils.GeneratePolicy{}
+	if policy.Generate != nil {
+		generate = *policy.Generate
+	}
+	return utils.GenerateSecret(generate)
+}
+
+// WebhookRotator asks an external service to mint a new credential.
+// The webhook receives a signed JSON description of the secret and must
+// answer with {"value": "<new value>"}.
+type WebhookRotator struct {
+	client *http.Client
+}
+
+func NewWebhookRotator() *WebhookRotator {
+	return &WebhookRotator{
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+			// a redirect could send the request to a host the url check refused
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+}
+
+// ValidateRotationWebhookURL accepts https urls only, on one of the hosts in
+// ROTATION_WEBHOOK_HOSTS (comma separated) when the variable is set
+func ValidateRotationWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Hostname() == "" {
+		return errors.New("webhook_url must be an https url")
+	}
+	allowed := identity.ParseList(os.Getenv("ROTATION_WEBHOOK_HOSTS"))
+	if len(allowed) > 0 && !slices.ContainsFunc(allowed, func(host string) bool { return strings.EqualFold(host, u.Hostname()) }) {
+		return errors.New("webhook_url host is not in ROTATION_WEBHOOK_HOSTS")
+	}
+	return nil
+}
+
+type webhookRotationRequest struct {
+	SecretID  string `json:"secret_id"`
+	ProjectID string `json:"project_id"`
+	Name      string `json:"name"`
+	Version   int    `json:"version"`
+}
+
+type webhookRotationResponse struct {
+	Value string `json:"value"`
+}
+
+func (w *WebhookRotator) Rotate(ctx context.Context, policy *models.RotationPolicy, secret *models.Secret) (string, error) {
+	if policy.WebhookURL == nil || *policy.WebhookURL == "" {
+		return "", errors.New("webhook rotator has no url")
+	}
+	// the allowlist may have changed since the policy was saved
+	if err := ValidateRotationWebhookURL(*policy.WebhookURL); err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(webhookRotationRequest{
+		SecretID:  secret.ID.String(),
+		ProjectID: secret.ProjectID.String(),
+		Name:      secret.Name,
+		Version:   secret.Version,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *policy.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if policy.WebhookSecret != nil {
+		key, err := utils.Decrypt(*policy.WebhookSecret)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(utils.SignatureHeader, utils.SignPayload([]byte(key), payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("rotation webhook failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("rotation webhook returned status %d", resp.StatusCode)
+	}
+
+	var out webhookRotationResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&out); err != nil {
+		return "", fmt.Errorf("invalid rotation webhook response: %w", err)
+	}
+	if out.Value == "" {
+		return "", errors.New("rotation webhook returned an empty value")
+	}
+
+	return out.Value, nil
+}
@@ -3,9 +3,11 @@ package services
 import (
 	"context"
 	"errors"
+	"log"
 	"strconv"
 	"time"
 
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
 	"github.com/akansha204/cryptex-secretservice/internal/models"
 	"github.com/akansha204/cryptex-secretservice/internal/repository"
 	"github.com/akansha204/cryptex-secretservice/internal/utils"
@@ -13,41 +15,101 @@ import (
 )
 
 type SecretService struct {
-	secretRepo   *repository.SecretRepository
-	projectRepo  *repository.ProjectRepository
-	AuditService *AuditService
+	secretRepo          *repository.SecretRepository
+	Authorizer          *Authorizer
+	AuditService        *AuditService
+	NotificationService *NotificationService
+	WebhookService      *WebhookService
+	LeaseService        *LeaseService
+	MFAService          *MFAService
+	changeRequestRepo   *repository.ChangeRequestRepository
 }
 
-func NewSecretService(secretRepo *repository.SecretRepository, projectRepo *repository.ProjectRepository, auditService *AuditService) *SecretService {
+func NewSecretService(secretRepo *repository.SecretRepository, authorizer *Authorizer, auditService *AuditService, notificationService *NotificationService, webhookService *WebhookService, leaseService *LeaseService, mfaService *MFAService, changeRequestRepo *repository.ChangeRequestRepository) *SecretService {
 	return &SecretService{
-		secretRepo:   secretRepo,
-		projectRepo:  projectRepo,
-		AuditService: auditService,
+		secretRepo:          secretRepo,
+		Authorizer:          authorizer,
+		AuditService:        auditService,
+		NotificationService: notificationService,
+		WebhookService:      webhookService,
+		LeaseService:        leaseService,
+		MFAService:          mfaService,
+		changeRequestRepo:   changeRequestRepo,
 	}
 }
 
+// SecretOptions carries the optional lifetime and lease settings of a secret.
+// On update, nil fields are left unchanged.
+type SecretOptions struct {
+	TTL       *time.Duration `json:"ttl,omitempty"`
+	ExpiresAt *time.Time     `json:"expires_at,omitempty"`
+	NotBefore *time.Time     `json:"not_before,omitempty"`
+
+	LeaseTTL    *time.Duration `json:"lease_ttl,omitempty"`     // every read issues a lease of this length; 0 disables leasing
+	LeaseMaxTTL *time.Duration `json:"lease_max_ttl,omitempty"` // how far renewals may extend a lease
+
+	MaxReads *int `json:"max_reads,omitempty"` // destroy the secret after this many reads; only used on create
+
+	Sensitive *bool `json:"sensitive,omitempty"` // reading needs step-up authentication
+
+	AllowedCIDRs *[]string `json:"allowed_cidrs,omitempty"` // where the secret may be read from; an empty list clears it
+}
+
+// PendingApprovalError is returned instead of applying a change in a project
+// that requires approval. The change waits in Request.
+type PendingApprovalError struct {
+	Request *models.ChangeRequest
+}
+
+func (e *PendingApprovalError) Error() string {
+	return "change request " + e.Request.ID.String() + " is waiting for approval"
+}
+
 func (s *SecretService) CreateSecret(
 	ctx context.Context,
 	userID string,
 	projectID string,
 	name string,
 	plaintextValue string,
-	ttlDays *int,
+	generate *utils.GeneratePolicy,
+	opts SecretOptions,
 ) (*models.Secret, error) {
 
 	userUUID := uuid.MustParse(userID)
-	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
+	project, err := s.Authorizer.Project(ctx, projectID)
 	if err != nil {
 		return nil, err
 	}
-	if project.DeletedAt != nil {
-		return nil, errors.New("project not available")
+	if err := s.Authorizer.CheckSecret(ctx, project, userID, name, PermCreate); err != nil {
+		return nil, err
 	}
-	if project == nil || project.UserID.String() != userID {
-		return nil, errors.New("unauthorized: project does not belong to this user")
+
+	if project.RequireApproval {
+		if err := checkProposal(project, &models.Secret{}, generate, opts); err != nil {
+			return nil, err
+		}
+		payload := &changePayload{Generate: generate, Options: opts}
+		if generate == nil {
+			payload.Value = &plaintextValue
+		}
+		return nil, s.propose(ctx, userUUID, project, nil, name, ChangeCreate, payload)
 	}
+	return s.createSecret(ctx, userUUID, project, name, plaintextValue, generate, opts)
+}
+
+// createSecret creates a secret the user was authorized for, or whose change
+// request was approved
+func (s *SecretService) createSecret(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	project *models.Project,
+	name string,
+	plaintextValue string,
+	generate *utils.GeneratePolicy,
+	opts SecretOptions,
+) (*models.Secret, error) {
 
-	latest, err := s.secretRepo.GetLatestVersion(ctx, projectID, name)
+	latest, err := s.secretRepo.GetLatestVersion(ctx, project.ID.String(), name)
 	if err != nil {
 		return nil, err
 	}
@@ -56,35 +118,50 @@ func (s *SecretService) CreateSecret(
 		newVersion = latest.Version + 1
 	}
 
+	if generate != nil {
+		plaintextValue, err = utils.GenerateSecret(*generate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	encryptedValue, err := utils.Encrypt(plaintextValue)
 	if err != nil {
 		return nil, err
 	}
 
-	var expiresAt *time.Time
-	if ttlDays == nil {
-		expiresAt = nil
-
-	} else {
-		if *ttlDays < 1 {
-			return nil, errors.New("ttl must be at least 1 day")
-		}
-
-		t := time.Now().Add(time.Duration(*ttlDays) * 24 * time.Hour)
-		expiresAt = &t
+	ttlSeconds, expiresAt, err := resolveLifetime(project, opts, time.Now())
+	if err != nil {
+		return nil, err
 	}
 
 	secret := &models.Secret{
 		ID:        uuid.New(),
-		ProjectID: uuid.MustParse(projectID),
+		ProjectID: project.ID,
 		Name:      name,
 		Value:     encryptedValue,
 		Version:   newVersion,
-		TTL:       ttlDays,
+		TTL:       ttlSeconds,
 		Revoked:   false,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
+		NotBefore: opts.NotBefore,
 		ExpiresAt: expiresAt,
+		Sensitive: opts.Sensitive != nil && *opts.Sensitive,
+	}
+	if err := applyLeaseOptions(secret, opts); err != nil {
+		return nil, err
+	}
+	if err := applyAllowedCIDRs(secret, opts); err != nil {
+		return nil, err
+	}
+	if opts.MaxReads != nil {
+		if *opts.MaxReads < 1 {
+			return nil, errors.New("max_reads must be at least 1")
+		}
+		maxReads, remaining := *opts.MaxReads, *opts.MaxReads
+		secret.MaxReads = &maxReads
+		secret.ReadsRemaining = &remaining
 	}
 
 	err = s.secretRepo.CreateSecret(ctx, secret)
@@ -99,6 +176,10 @@ func (s *SecretService) CreateSecret(
 		"CREATE_SECRET",
 		"Secret created with version "+strconv.Itoa(newVersion),
 	)
+	s.WebhookService.Emit(ctx, secret.ProjectID, &secret.ID, &userUUID, EventSecretCreated, map[string]any{
+		"name":    secret.Name,
+		"version": secret.Version,
+	})
 
 	return secret, nil
 }
@@ -108,39 +189,154 @@ func (s *SecretService) GetSecretByID(
 	userID string,
 	projectID string,
 	secretID string,
-) (*models.Secret, string, error) {
+) (*models.Secret, string, *models.Lease, error) {
 
-	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
-	if err != nil || project == nil || project.DeletedAt != nil {
-		return nil, "", errors.New("project not found")
-	}
-	if project.UserID.String() != userID {
-		return nil, "", errors.New("unauthorized")
-	}
-	secret, err := s.secretRepo.GetSecretByID(ctx, secretID)
+	project, secret, decision, err := s.Authorizer.authorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, PermRead)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
 	}
 
-	if secret == nil || secret.DeletedAt != nil {
-		return nil, "", errors.New("secret not found")
+	if err := s.checkNetwork(ctx, userID, project, secret); err != nil {
+		return nil, "", nil, err
 	}
 
 	if secret.ExpiresAt != nil && time.Now().After(*secret.ExpiresAt) {
-		return nil, "", errors.New("secret has expired")
+		return nil, "", nil, errors.New("secret has expired")
+	}
+
+	if secret.NotBefore != nil && time.Now().Before(*secret.NotBefore) {
+		return nil, "", nil, errors.New("secret is not active yet")
 	}
 
 	if secret.Revoked {
-		return nil, "", errors.New("secret is revoked")
+		return nil, "", nil, errors.New("secret is revoked")
+	}
+
+	if secret.Sensitive {
+		if err := s.MFAService.StepUp(ctx, userID, secret); err != nil {
+			return nil, "", nil, err
+		}
 	}
 
 	//decrypt secret value
 	plaintext, err := utils.Decrypt(secret.Value)
 	if err != nil {
-		return nil, "", err
+		return nil, "", nil, err
+	}
+
+	// reads of members are not audited, reads during a break-glass session or
+	// under a temporary grant are
+	userUUID := uuid.MustParse(userID)
+	switch {
+	case decision.BreakGlass != nil:
+		s.AuditService.Log(
+			ctx,
+			&userUUID,
+			&secret.ProjectID,
+			&secret.ID,
+			"READ_SECRET",
+			"Secret "+secret.Name+" read during break-glass session "+decision.BreakGlass.String(),
+		)
+	case decision.GrantID != nil:
+		s.AuditService.LogGrant(
+			ctx,
+			&userUUID,
+			&secret.ProjectID,
+			&secret.ID,
+			decision.GrantID,
+			"READ_SECRET",
+			"Secret "+secret.Name+" read under access grant "+decision.GrantID.String(),
+		)
+	}
+
+	if secret.ReadsRemaining != nil {
+		remaining, ok, err := s.secretRepo.ConsumeRead(ctx, secretID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if !ok {
+			// another reader took the last read
+			return nil, "", nil, errors.New("secret not found")
+		}
+		secret.ReadsRemaining = &remaining
+		if remaining == 0 {
+			s.destroyAfterLastRead(ctx, uuid.MustParse(userID), secret)
+			return secret, plaintext, nil, nil
+		}
+	}
+
+	var lease *models.Lease
+	if secret.LeaseTTL != nil {
+		lease, err = s.LeaseService.Issue(ctx, uuid.MustParse(userID), secret)
+		if err != nil {
+			return nil, "", nil, err
+		}
+	}
+
+	return secret, plaintext, lease, nil
+}
+
+// checkNetwork refuses a read from outside the allowlists of the project, the
+// secret and, for machine callers, their service account. Every list that is
+// set must contain the caller's address.
+func (s *SecretService) checkNetwork(ctx context.Context, userID string, project *models.Project, secret *models.Secret) error {
+	id := identity.FromContext(ctx)
+	if id == nil {
+		// background jobs have no address
+		return nil
+	}
+
+	var denied string
+	switch {
+	case !utils.IPAllowed(id.IP, project.AllowedCIDRs):
+		denied = "project"
+	case !utils.IPAllowed(id.IP, secret.AllowedCIDRs):
+		denied = "secret"
+	case id.ServiceAccount != nil && !utils.IPAllowed(id.IP, id.ServiceAccount.AllowedCIDRs):
+		denied = "service account"
+	default:
+		return nil
+	}
+
+	userUUID := uuid.MustParse(userID)
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"SECRET_IP_DENIED",
+		"Read of secret "+secret.Name+" from "+id.IP+" denied by the "+denied+" allowlist",
+	)
+	return errors.New("forbidden: secret cannot be read from " + id.IP)
+}
+
+// ListSecrets returns the metadata of the live secrets of a project, without
+// their values. Users who may not list the whole project only see the secrets
+// a policy lets them list.
+func (s *SecretService) ListSecrets(ctx context.Context, userID string, projectID string) ([]models.Secret, error) {
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	projectErr := s.Authorizer.Check(ctx, project, userID, PermList)
+
+	secrets, err := s.secretRepo.GetSecretsByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
 	}
 
-	return secret, plaintext, nil
+	visible := make([]models.Secret, 0, len(secrets))
+	for _, secret := range secrets {
+		if projectErr != nil && s.Authorizer.CheckSecret(ctx, project, userID, secret.Name, PermList) != nil {
+			continue
+		}
+		secret.Value = ""
+		visible = append(visible, secret)
+	}
+	if projectErr != nil && len(visible) == 0 {
+		return nil, projectErr
+	}
+	return visible, nil
 }
 
 func (s *SecretService) UpdateSecret(
@@ -149,25 +345,55 @@ func (s *SecretService) UpdateSecret(
 	projectID string,
 	secretID string,
 	newValue *string,
-	ttlDays *int,
+	generate *utils.GeneratePolicy,
+	opts SecretOptions,
 ) (*models.Secret, error) {
 	userUUID := uuid.MustParse(userID)
 
-	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
-	if err != nil || project == nil || project.UserID.String() != userID {
-		return nil, errors.New("unauthorized")
-	}
-
-	existing, err := s.secretRepo.GetSecretByID(ctx, secretID)
-	if err != nil || existing == nil || existing.DeletedAt != nil {
-		return nil, errors.New("secret not found")
+	project, existing, err := s.Authorizer.AuthorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, PermUpdate)
+	if err != nil {
+		return nil, err
 	}
 	if existing.Revoked {
 		return nil, errors.New("cannot update a revoked secret")
 	}
+	if opts.Sensitive != nil && existing.Sensitive && !*opts.Sensitive {
+		// otherwise clearing the flag would skip step-up for the next read
+		if err := s.MFAService.StepUp(ctx, userID, existing); err != nil {
+			return nil, err
+		}
+	}
+
+	if project.RequireApproval {
+		if err := checkProposal(project, existing, generate, opts); err != nil {
+			return nil, err
+		}
+		payload := &changePayload{Value: newValue, Generate: generate, Options: opts}
+		return nil, s.propose(ctx, userUUID, project, existing, existing.Name, ChangeUpdate, payload)
+	}
+	return s.updateSecret(ctx, userUUID, project, existing, newValue, generate, opts)
+}
 
+// updateSecret changes a secret the user was authorized for, or whose change
+// request was approved
+func (s *SecretService) updateSecret(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	project *models.Project,
+	existing *models.Secret,
+	newValue *string,
+	generate *utils.GeneratePolicy,
+	opts SecretOptions,
+) (*models.Secret, error) {
 	valueChanged := false
 
+	if generate != nil {
+		generated, err := utils.GenerateSecret(*generate)
+		if err != nil {
+			return nil, err
+		}
+		newValue = &generated
+	}
 	if newValue != nil {
 		encrypted, err := utils.Encrypt(*newValue)
 		if err != nil {
@@ -176,14 +402,32 @@ func (s *SecretService) UpdateSecret(
 		existing.Value = encrypted
 		valueChanged = true
 	}
-	if ttlDays != nil {
-		if *ttlDays < 1 {
-			return nil, errors.New("ttl must be at least 1 day")
+	if opts.TTL != nil || opts.ExpiresAt != nil || opts.NotBefore != nil {
+		if opts.NotBefore == nil {
+			opts.NotBefore = existing.NotBefore
+		}
+		if opts.TTL == nil && opts.ExpiresAt == nil && existing.TTL != nil {
+			// only the activation time moved, keep the same lifetime from there
+			ttl := time.Duration(*existing.TTL) * time.Second
+			opts.TTL = &ttl
 		}
 
-		existing.TTL = ttlDays
-		expires := time.Now().Add(time.Duration(*ttlDays) * 24 * time.Hour)
-		existing.ExpiresAt = &expires
+		ttlSeconds, expiresAt, err := resolveLifetime(project, opts, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		existing.TTL = ttlSeconds
+		existing.NotBefore = opts.NotBefore
+		existing.ExpiresAt = expiresAt
+	}
+	if err := applyLeaseOptions(existing, opts); err != nil {
+		return nil, err
+	}
+	if err := applyAllowedCIDRs(existing, opts); err != nil {
+		return nil, err
+	}
+	if opts.Sensitive != nil {
+		existing.Sensitive = *opts.Sensitive
 	}
 
 	if valueChanged {
@@ -191,7 +435,7 @@ func (s *SecretService) UpdateSecret(
 	}
 	existing.UpdatedAt = time.Now()
 
-	err = s.secretRepo.UpdateSecret(ctx, existing)
+	err := s.secretRepo.UpdateSecret(ctx, existing)
 	if err != nil {
 		return nil, err
 	}
@@ -204,6 +448,11 @@ func (s *SecretService) UpdateSecret(
 		"UPDATE_SECRET",
 		"Secret updated (version "+strconv.Itoa(existing.Version)+")",
 	)
+	s.WebhookService.Emit(ctx, existing.ProjectID, &existing.ID, &userUUID, EventSecretUpdated, map[string]any{
+		"name":          existing.Name,
+		"version":       existing.Version,
+		"value_changed": valueChanged,
+	})
 
 	return existing, nil
 }
@@ -216,18 +465,17 @@ func (s *SecretService) DeleteSecret(
 ) error {
 
 	userUUID := uuid.MustParse(userID)
-	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
-	if err != nil || project == nil || project.DeletedAt != nil {
-		return errors.New("unauthorized")
+	project, secret, err := s.Authorizer.AuthorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, PermDelete)
+	if err != nil {
+		return err
 	}
-	if project.UserID.String() != userID {
-		return errors.New("unauthorized")
+	if project.RequireApproval {
+		return s.propose(ctx, userUUID, project, secret, secret.Name, ChangeDelete, nil)
 	}
+	return s.deleteSecret(ctx, userUUID, secret)
+}
 
-	secret, err := s.secretRepo.GetSecretByID(ctx, secretID)
-	if err != nil || secret == nil || secret.DeletedAt != nil {
-		return errors.New("secret not found")
-	}
+func (s *SecretService) deleteSecret(ctx context.Context, userUUID uuid.UUID, secret *models.Secret) error {
 	s.AuditService.Log(
 		ctx,
 		&userUUID,
@@ -237,7 +485,21 @@ func (s *SecretService) DeleteSecret(
 		"Secret deleted",
 	)
 
-	return s.secretRepo.SoftDeleteSecret(ctx, secretID)
+	err := s.secretRepo.SoftDeleteSecret(ctx, secret.ID.String())
+	if err != nil {
+		return err
+	}
+
+	s.WebhookService.Emit(ctx, secret.ProjectID, &secret.ID, &userUUID, EventSecretDeleted, map[string]any{
+		"name": secret.Name,
+	})
+
+	s.NotificationService.Notify(ctx, secret.ProjectID, &secret.ID, NotifySecretDeleted, map[string]any{
+		"name":       secret.Name,
+		"deleted_by": userUUID.String(),
+	})
+
+	return nil
 
 }
 
@@ -250,23 +512,21 @@ func (s *SecretService) RevokeSecret(
 
 	userUUID := uuid.MustParse(userID)
 
-	project, err := s.projectRepo.GetProjectByID(ctx, projectID)
-	if err != nil || project == nil || project.UserID.String() != userID {
-		return errors.New("unauthorized")
-	}
-
-	secret, err := s.secretRepo.GetSecretByID(ctx, secretID)
-	if err != nil || secret == nil {
-		return errors.New("secret not found")
+	project, secret, err := s.Authorizer.AuthorizeSecret(ctx, s.secretRepo, userID, projectID, secretID, PermRevoke)
+	if err != nil {
+		return err
 	}
-	if secret.DeletedAt != nil {
-		return errors.New("secret not found")
+	if project.RequireApproval {
+		return s.propose(ctx, userUUID, project, secret, secret.Name, ChangeRevoke, nil)
 	}
+	return s.revokeSecret(ctx, userUUID, secret)
+}
 
+func (s *SecretService) revokeSecret(ctx context.Context, userUUID uuid.UUID, secret *models.Secret) error {
 	secret.Revoked = true
 	secret.UpdatedAt = time.Now()
 
-	err = s.secretRepo.UpdateSecret(ctx, secret)
+	err := s.secretRepo.UpdateSecret(ctx, secret)
 	if err != nil {
 		return err
 	}
@@ -279,6 +539,232 @@ func (s *SecretService) RevokeSecret(
 		"REVOKE_SECRET",
 		"Secret revoked",
 	)
+	s.WebhookService.Emit(ctx, secret.ProjectID, &secret.ID, &userUUID, EventSecretRevoked, map[string]any{
+		"name": secret.Name,
+	})
+	if err := s.LeaseService.RevokeForSecret(ctx, &userUUID, secret.ID); err != nil {
+		log.Printf("[LEASE ERROR] cannot revoke leases of secret %s: %v", secret.ID, err)
+	}
+
+	s.NotificationService.Notify(ctx, secret.ProjectID, &secret.ID, NotifySecretRevoked, map[string]any{
+		"name":       secret.Name,
+		"revoked_by": userUUID.String(),
+	})
+
+	return nil
+}
+
+// TrashedSecret is a soft-deleted secret with the time the purge job removes it
+type TrashedSecret struct {
+	models.Secret
+	PurgeAt time.Time
+}
+
+func (s *SecretService) ListTrash(ctx context.Context, userID string, projectID string) ([]TrashedSecret, error) {
+	if _, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList); err != nil {
+		return nil, err
+	}
+
+	secrets, err := s.secretRepo.GetDeletedSecretsByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	retention := utils.PurgeRetention()
+	trashed := make([]TrashedSecret, 0, len(secrets))
+	for _, secret := range secrets {
+		trashed = append(trashed, TrashedSecret{Secret: secret, PurgeAt: secret.DeletedAt.Add(retention)})
+	}
+	return trashed, nil
+}
+
+func (s *SecretService) RestoreSecret(ctx context.Context, userID string, projectID string, secretID string) (*models.Secret, error) {
+	userUUID := uuid.MustParse(userID)
+
+	secret, err := s.trashedSecret(ctx, userID, projectID, secretID, PermDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	conflict, err := s.secretRepo.GetLatestVersion(ctx, projectID, secret.Name)
+	if err != nil {
+		return nil, err
+	}
+	if conflict != nil {
+		return nil, errors.New("a live secret named " + secret.Name + " already exists, delete it before restoring")
+	}
+
+	if err := s.secretRepo.RestoreSecret(ctx, secretID); err != nil {
+		return nil, err
+	}
+	secret.DeletedAt = nil
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"RESTORE_SECRET",
+		"Secret restored from trash (version "+strconv.Itoa(secret.Version)+")",
+	)
+	return secret, nil
+}
 
+// PurgeSecret permanently deletes a secret from the trash without waiting for the purge job
+func (s *SecretService) PurgeSecret(ctx context.Context, userID string, projectID string, secretID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	secret, err := s.trashedSecret(ctx, userID, projectID, secretID, PermOwn)
+	if err != nil {
+		return err
+	}
+
+	if err := s.secretRepo.DestroySecret(ctx, secretID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"PURGE_SECRET",
+		"Secret permanently deleted from trash",
+	)
+	return nil
+}
+
+// trashedSecret loads a soft-deleted secret of a live project where the user has perm on it
+func (s *SecretService) trashedSecret(ctx context.Context, userID string, projectID string, secretID string, perm Permission) (*models.Secret, error) {
+	project, err := s.Authorizer.Project(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := s.secretRepo.GetDeletedSecretByID(ctx, secretID)
+	if err != nil || secret == nil || secret.ProjectID != project.ID {
+		// do not reveal trash contents to users without access to the project
+		if err := s.Authorizer.Check(ctx, project, userID, perm); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("secret not found in trash")
+	}
+	if err := s.Authorizer.CheckSecret(ctx, project, userID, secret.Name, perm); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+// destroyAfterLastRead permanently deletes a read-limited secret once its last
+// read was handed out. The read is already consumed, so a failed delete only
+// leaves an unreadable row for the purge job.
+func (s *SecretService) destroyAfterLastRead(ctx context.Context, userID uuid.UUID, secret *models.Secret) {
+	if err := s.secretRepo.DestroySecret(ctx, secret.ID.String()); err != nil {
+		log.Printf("[SECRET ERROR] cannot destroy read-limited secret %s: %v", secret.ID, err)
+	}
+	if err := s.LeaseService.RevokeForSecret(ctx, &userID, secret.ID); err != nil {
+		log.Printf("[LEASE ERROR] cannot revoke leases of secret %s: %v", secret.ID, err)
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userID,
+		&secret.ProjectID,
+		&secret.ID,
+		"SECRET_DESTROYED",
+		"Secret destroyed after reaching its read limit of "+strconv.Itoa(*secret.MaxReads),
+	)
+	s.WebhookService.Emit(ctx, secret.ProjectID, &secret.ID, &userID, EventSecretDeleted, map[string]any{
+		"name":   secret.Name,
+		"reason": "read limit reached",
+	})
+}
+
+// resolveLifetime turns the requested ttl, expiry and activation time into the
+// stored ttl (in seconds) and expiry, enforcing the project's TTL bounds.
+// The lifetime counts from the activation time when it is in the future.
+func resolveLifetime(project *models.Project, opts SecretOptions, now time.Time) (*int64, *time.Time, error) {
+	if opts.TTL != nil && opts.ExpiresAt != nil {
+		return nil, nil, errors.New("use either ttl or expires_at, not both")
+	}
+
+	start := now
+	if opts.NotBefore != nil && opts.NotBefore.After(now) {
+		start = *opts.NotBefore
+	}
+
+	var expiresAt *time.Time
+	switch {
+	case opts.TTL != nil:
+		if *opts.TTL <= 0 {
+			return nil, nil, errors.New("ttl must be positive")
+		}
+		t := start.Add(*opts.TTL)
+		expiresAt = &t
+	case opts.ExpiresAt != nil:
+		if !opts.ExpiresAt.After(start) {
+			return nil, nil, errors.New("expires_at must be after now and after not_before")
+		}
+		t := *opts.ExpiresAt
+		expiresAt = &t
+	case project.MaxTTL != nil:
+		// the project does not allow secrets that never expire
+		t := start.Add(time.Duration(*project.MaxTTL) * time.Second)
+		expiresAt = &t
+	default:
+		return nil, nil, nil
+	}
+
+	lifetime := expiresAt.Sub(start)
+	if project.MinTTL != nil && lifetime < time.Duration(*project.MinTTL)*time.Second {
+		return nil, nil, errors.New("ttl is below the project minimum of " + (time.Duration(*project.MinTTL) * time.Second).String())
+	}
+	if project.MaxTTL != nil && lifetime > time.Duration(*project.MaxTTL)*time.Second {
+		return nil, nil, errors.New("ttl exceeds the project maximum of " + (time.Duration(*project.MaxTTL) * time.Second).String())
+	}
+
+	seconds := int64(lifetime / time.Second)
+	return &seconds, expiresAt, nil
+}
+
+// applyAllowedCIDRs sets or (with an empty list) clears the network allowlist of a secret
+func applyAllowedCIDRs(secret *models.Secret, opts SecretOptions) error {
+	if opts.AllowedCIDRs == nil {
+		return nil
+	}
+	cidrs, err := utils.NormalizeCIDRs(*opts.AllowedCIDRs)
+	if err != nil {
+		return err
+	}
+	secret.AllowedCIDRs = cidrs
+	return nil
+}
+
+// applyLeaseOptions enables, changes or (with a zero lease ttl) disables leasing on a secret
+func applyLeaseOptions(secret *models.Secret, opts SecretOptions) error {
+	if opts.LeaseTTL != nil {
+		if *opts.LeaseTTL < 0 {
+			return errors.New("lease_ttl cannot be negative")
+		}
+		if *opts.LeaseTTL == 0 {
+			secret.LeaseTTL = nil
+			secret.LeaseMaxTTL = nil
+			return nil
+		}
+		seconds := int64(*opts.LeaseTTL / time.Second)
+		if seconds < 1 {
+			return errors.New("lease_ttl must be at least one second")
+		}
+		secret.LeaseTTL = &seconds
+	}
+	if opts.LeaseMaxTTL != nil {
+		if secret.LeaseTTL == nil {
+			return errors.New("lease_max_ttl requires lease_ttl")
+		}
+		seconds := int64(*opts.LeaseMaxTTL / time.Second)
+		if seconds < *secret.LeaseTTL {
+			return errors.New("lease_max_ttl cannot be shorter than lease_ttl")
+		}
+		secret.LeaseMaxTTL = &seconds
+	}
 	return nil
 }
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/identity"
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+// ServiceTokenPrefix marks service account tokens, so auth can tell them from other bearer tokens
+const ServiceTokenPrefix = "cx_sa_"
+
+const (
+	defaultServiceTokenTTL = 30 * 24 * time.Hour
+	maxServiceTokenTTL     = 365 * 24 * time.Hour
+)
+
+// what a service token can be allowed to do; managing the project stays with people
+var serviceTokenScopes = []string{string(PermRead), string(PermList), string(PermCreate), string(PermUpdate), string(PermDelete), string(PermRevoke)}
+
+type ServiceAccountService struct {
+	repo         *repository.ServiceAccountRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+}
+
+func NewServiceAccountService(repo *repository.ServiceAccountRepository, authorizer *Authorizer, auditService *AuditService) *ServiceAccountService {
+	return &ServiceAccountService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+	}
+}
+
+// ServiceTokenInput describes a new token. Nil TTL means the default of 30 days.
+type ServiceTokenInput struct {
+	Label        string
+	Scopes       []string
+	AllowedCIDRs []string
+	TTL          *time.Duration
+}
+
+func (s *ServiceAccountService) CreateServiceAccount(ctx context.Context, userID string, projectID string, name string, description *string, pathPrefix string, allowedCIDRs []string) (*models.ServiceAccount, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("name is required")
+	}
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+	cidrs, err := utils.NormalizeCIDRs(allowedCIDRs)
+	if err != nil {
+		return nil, err
+	}
+
+	account := &models.ServiceAccount{
+		ProjectID:    project.ID,
+		Name:         name,
+		Description:  description,
+		PathPrefix:   pathPrefix,
+		AllowedCIDRs: cidrs,
+		CreatedBy:    userUUID,
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	}
+	if err := s.repo.CreateServiceAccount(ctx, account); err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			return nil, errors.New("a service account named " + name + " already exists in this project")
+		}
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"CREATE_SERVICE_ACCOUNT",
+		"Service account "+name+" created for "+ResourcePath(project.ID.String(), pathPrefix)+"*",
+	)
+	return account, nil
+}
+
+func (s *ServiceAccountService) ListServiceAccounts(ctx context.Context, userID string, projectID string) ([]models.ServiceAccount, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetServiceAccountsByProject(ctx, project.ID.String())
+}
+
+// DeleteServiceAccount deletes a service account; its tokens stop working at once
+func (s *ServiceAccountService) DeleteServiceAccount(ctx context.Context, userID string, projectID string, accountID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, account, err := s.projectAccount(ctx, userID, projectID, accountID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteServiceAccount(ctx, accountID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"DELETE_SERVICE_ACCOUNT",
+		"Service account "+account.Name+" deleted with its tokens",
+	)
+	return nil
+}
+
+// CreateToken issues an API token for a service account. The token is
+// returned once; only its hash is stored.
+func (s *ServiceAccountService) CreateToken(ctx context.Context, userID string, projectID string, accountID string, input ServiceTokenInput) (*models.ServiceToken, string, error) {
+	userUUID := uuid.MustParse(userID)
+
+	scopes := input.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{string(PermRead), string(PermList)}
+	}
+	for _, scope := range scopes {
+		if !containsString(serviceTokenScopes, scope) {
+			return nil, "", errors.New("unknown scope " + scope)
+		}
+	}
+	cidrs, err := utils.NormalizeCIDRs(input.AllowedCIDRs)
+	if err != nil {
+		return nil, "", err
+	}
+	ttl := defaultServiceTokenTTL
+	if input.TTL != nil {
+		if *input.TTL <= 0 || *input.TTL > maxServiceTokenTTL {
+			return nil, "", errors.New("token ttl must be between 1s and " + maxServiceTokenTTL.String())
+		}
+		ttl = *input.TTL
+	}
+
+	project, account, err := s.projectAccount(ctx, userID, projectID, accountID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	plaintext, err := utils.RandomToken(ServiceTokenPrefix, 32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token := &models.ServiceToken{
+		ServiceAccountID: account.ID,
+		ProjectID:        project.ID,
+		TokenHash:        utils.HashToken(plaintext),
+		TokenPrefix:      plaintext[:len(ServiceTokenPrefix)+6],
+		Label:            input.Label,
+		Scopes:           scopes,
+		AllowedCIDRs:     cidrs,
+		CreatedBy:        userUUID,
+		ExpiresAt:        time.Now().Add(ttl),
+		CreatedAt:        time.Now(),
+	}
+	if err := s.repo.CreateToken(ctx, token); err != nil {
+		return nil, "", err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"CREATE_SERVICE_TOKEN",
+		"Token "+token.TokenPrefix+"... issued for service account "+account.Name+" with scopes "+strings.Join(scopes, ",")+" until "+token.ExpiresAt.Format(time.RFC3339),
+	)
+	return token, plaintext, nil
+}
+
+func (s *ServiceAccountService) ListTokens(ctx context.Context, userID string, projectID string, accountID string) ([]models.ServiceToken, error) {
+	_, account, err := s.projectAccount(ctx, userID, projectID, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetTokensByServiceAccount(ctx, account.ID.String())
+}
+
+func (s *ServiceAccountService) RevokeToken(ctx context.Context, userID string, projectID string, accountID string, tokenID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, account, err := s.projectAccount(ctx, userID, projectID, accountID)
+	if err != nil {
+		return err
+	}
+	if _, err := uuid.Parse(tokenID); err != nil {
+		return errors.New("token not found")
+	}
+	revoked, err := s.repo.RevokeToken(ctx, accountID, tokenID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("token not found or already revoked")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"REVOKE_SERVICE_TOKEN",
+		"Token "+tokenID+" of service account "+account.Name+" revoked",
+	)
+	return nil
+}
+
+// VerifyToken resolves a service token presented from ip to the identity of
+// its service account. It is called by the token auth middleware.
+func (s *ServiceAccountService) VerifyToken(ctx context.Context, plaintext string, ip string) (*identity.Identity, error) {
+	token, err := s.repo.GetTokenByHash(ctx, utils.HashToken(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	if token == nil || token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	account, err := s.repo.GetServiceAccountByID(ctx, token.ServiceAccountID.String())
+	if err != nil || account == nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	if !utils.IPAllowed(ip, token.AllowedCIDRs) {
+		s.AuditService.Log(
+			ctx,
+			&account.ID,
+			&account.ProjectID,
+			nil,
+			"SERVICE_TOKEN_IP_DENIED",
+			"Token "+token.TokenPrefix+"... used from "+ip+", outside its allowed networks",
+		)
+		return nil, errors.New("token is not allowed from this address")
+	}
+
+	if err := s.repo.TouchToken(ctx, token.ID.String(), ip); err != nil {
+		// tracking must not break authentication
+		log.Printf("[TOKEN ERROR] cannot record use of token %s: %v", token.ID, err)
+	}
+
+	return &identity.Identity{
+		UserID: account.ID.String(),
+		ServiceAccount: &identity.ServiceAccount{
+			ID:         account.ID.String(),
+			ProjectID:  account.ProjectID.String(),
+			PathPrefix: account.PathPrefix,
+			TokenID:    token.ID.String(),
+			Scopes:     token.Scopes,
+
+			AllowedCIDRs: account.AllowedCIDRs,
+		},
+	}, nil
+}
+
+// projectAccount loads a service account of a project the user may manage
+func (s *ServiceAccountService) projectAccount(ctx context.Context, userID string, projectID string, accountID string) (*models.Project, *models.ServiceAccount, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := uuid.Parse(accountID); err != nil {
+		return nil, nil, errors.New("service account not found")
+	}
+	account, err := s.repo.GetServiceAccountByID(ctx, accountID)
+	if err != nil || account == nil || account.ProjectID != project.ID {
+		return nil, nil, errors.New("service account not found")
+	}
+	return project, account, nil
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultShareTTL = 24 * time.Hour
+	maxShareTTL     = 30 * 24 * time.Hour
+	maxShareViews   = 100
+)
+
+var errShareUnavailable = errors.New("share not found or no longer available")
+
+type ShareService struct {
+	repo          *repository.ShareRepository
+	Authorizer    *Authorizer
+	SecretService *SecretService
+	AuditService  *AuditService
+}
+
+func NewShareService(repo *repository.ShareRepository, authorizer *Authorizer, secretService *SecretService, auditService *AuditService) *ShareService {
+	return &ShareService{
+		repo:          repo,
+		Authorizer:    authorizer,
+		SecretService: secretService,
+		AuditService:  auditService,
+	}
+}
+
+// ShareInput describes a new share. Exactly one of Value and SecretID is set.
+type ShareInput struct {
+	Value      *string // ad-hoc value
+	SecretID   *string // snapshot the current value of a secret of the project
+	Label      string
+	TTL        *time.Duration
+	MaxViews   *int
+	Passphrase string
+}
+
+// CreateShare encrypts the value under a key derived from a fresh random link
+// fragment. The fragment is returned once and never stored, so the link is the
+// only way to read the share.
+func (s *ShareService) CreateShare(ctx context.Context, userID string, projectID string, input ShareInput) (*models.Share, string, error) {
+	userUUID := uuid.MustParse(userID)
+
+	if (input.Value == nil) == (input.SecretID == nil) {
+		return nil, "", errors.New("either value or secret_id is required")
+	}
+
+	ttl := defaultShareTTL
+	if input.TTL != nil {
+		if *input.TTL <= 0 || *input.TTL > maxShareTTL {
+			return nil, "", errors.New("share ttl must be between 1s and " + maxShareTTL.String())
+		}
+		ttl = *input.TTL
+	}
+	maxViews := 1
+	if input.MaxViews != nil {
+		if *input.MaxViews < 1 || *input.MaxViews > maxShareViews {
+			return nil, "", errors.New("max_views must be between 1 and " + strconv.Itoa(maxShareViews))
+		}
+		maxViews = *input.MaxViews
+	}
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermCreate)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var plaintext string
+	var secretUUID *uuid.UUID
+	if input.SecretID != nil {
+		// a snapshot is a normal read of the secret
+		secret, value, _, err := s.SecretService.GetSecretByID(ctx, userID, projectID, *input.SecretID)
+		if err != nil {
+			return nil, "", err
+		}
+		plaintext = value
+		secretUUID = &secret.ID
+	} else {
+		plaintext = *input.Value
+		if plaintext == "" {
+			return nil, "", errors.New("value cannot be empty")
+		}
+	}
+
+	fragment := make([]byte, 32)
+	salt := make([]byte, 16)
+	if _, err := rand.Read(fragment); err != nil {
+		return nil, "", err
+	}
+	if _, err := rand.Read(salt); err != nil {
+		return nil, "", err
+	}
+
+	key, err := utils.DeriveShareKey(fragment, salt, input.Passphrase)
+	if err != nil {
+		return nil, "", err
+	}
+	ciphertext, err := utils.EncryptWithKey(key, plaintext)
+	if err != nil {
+		return nil, "", err
+	}
+
+	share := &models.Share{
+		ID:                 uuid.New(),
+		ProjectID:          project.ID,
+		SecretID:           secretUUID,
+		CreatedBy:          userUUID,
+		Label:              input.Label,
+		Ciphertext:         ciphertext,
+		Salt:               base64.StdEncoding.EncodeToString(salt),
+		PassphraseRequired: input.Passphrase != "",
+		MaxViews:           maxViews,
+		ExpiresAt:          time.Now().Add(ttl),
+		CreatedAt:          time.Now(),
+	}
+	if err := s.repo.CreateShare(ctx, share); err != nil {
+		return nil, "", err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&share.ProjectID,
+		share.SecretID,
+		"SHARE_CREATED",
+		"Share "+share.ID.String()+" created for "+strconv.Itoa(maxViews)+" view(s) until "+share.ExpiresAt.Format(time.RFC3339),
+	)
+
+	return share, base64.RawURLEncoding.EncodeToString(fragment), nil
+}
+
+func (s *ShareService) ListShares(ctx context.Context, userID string, projectID string) ([]models.Share, error) {
+	if _, err := s.Authorizer.Authorize(ctx, userID, projectID, PermList); err != nil {
+		return nil, err
+	}
+	return s.repo.GetSharesByProject(ctx, projectID)
+}
+
+func (s *ShareService) RevokeShare(ctx context.Context, userID string, projectID string, shareID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermRevoke)
+	if err != nil {
+		return err
+	}
+	share, err := s.repo.GetShareByID(ctx, shareID)
+	if err != nil || share == nil || share.ProjectID != project.ID {
+		return errors.New("share not found")
+	}
+
+	revoked, err := s.repo.RevokeShare(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return errors.New("share is already revoked")
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&share.ProjectID,
+		share.SecretID,
+		"SHARE_REVOKED",
+		"Share "+share.ID.String()+" revoked",
+	)
+	return nil
+}
+
+// ViewShare decrypts a share with the key from its link and counts the view.
+// It needs no authentication: the link fragment (and passphrase) is the credential.
+func (s *ShareService) ViewShare(ctx context.Context, shareID string, fragment string, passphrase string) (string, *models.Share, error) {
+	now := time.Now()
+
+	share, err := s.repo.GetShareByID(ctx, shareID)
+	if err != nil || share == nil {
+		return "", nil, errShareUnavailable
+	}
+	if share.RevokedAt != nil || !share.ExpiresAt.After(now) || share.Views >= share.MaxViews || share.Ciphertext == "" {
+		return "", nil, errShareUnavailable
+	}
+	if share.PassphraseRequired && passphrase == "" {
+		return "", nil, errors.New("passphrase required")
+	}
+
+	// decrypt before counting the view, so a wrong link or passphrase does not use one up
+	plaintext, err := s.decryptShare(share, fragment, passphrase)
+	if err != nil {
+		s.AuditService.Log(
+			ctx,
+			nil,
+			&share.ProjectID,
+			share.SecretID,
+			"SHARE_VIEW_FAILED",
+			"Share "+share.ID.String()+" opened with a wrong link key or passphrase",
+		)
+		return "", nil, errors.New("invalid link or passphrase")
+	}
+
+	viewed, err := s.repo.ConsumeView(ctx, shareID, now)
+	if err != nil {
+		return "", nil, err
+	}
+	if viewed == nil {
+		return "", nil, errShareUnavailable
+	}
+	if viewed.Views >= viewed.MaxViews {
+		if err := s.repo.ScrubShare(ctx, shareID); err != nil {
+			log.Printf("[SHARE ERROR] cannot scrub share %s: %v", shareID, err)
+		}
+	}
+
+	s.AuditService.Log(
+		ctx,
+		nil,
+		&viewed.ProjectID,
+		viewed.SecretID,
+		"SHARE_VIEWED",
+		"Share "+viewed.ID.String()+" viewed ("+strconv.Itoa(viewed.Views)+" of "+strconv.Itoa(viewed.MaxViews)+")",
+	)
+
+	return plaintext, viewed, nil
+}
+
+func (s *ShareService) decryptShare(share *models.Share, fragment string, passphrase string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(fragment)
+	if err != nil {
+		return "", err
+	}
+	salt, err := base64.StdEncoding.DecodeString(share.Salt)
+	if err != nil {
+		return "", err
+	}
+	derived, err := utils.DeriveShareKey(key, salt, passphrase)
+	if err != nil {
+		return "", err
+	}
+	return utils.DecryptWithKey(derived, share.Ciphertext)
+}
@@ -0,0 +1,428 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	EventSecretCreated      = "secret.created"
+	EventSecretUpdated      = "secret.updated"
+	EventSecretRotated      = "secret.rotated"
+	EventSecretRevoked      = "secret.revoked"
+	EventSecretDeleted      = "secret.deleted"
+	EventProjectCreated     = "project.created"
+	EventProjectUpdated     = "project.updated"
+	EventProjectDeleted     = "project.deleted"
+	EventProjectTransferred = "project.transferred"
+	EventLeaseRevoked       = "lease.revoked"
+	EventLeaseExpired       = "lease.expired"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookMaxAttempts  = 8
+	webhookRetryBase    = 30 * time.Second
+	webhookRetryMax     = 6 * time.Hour
+	webhookClaimTimeout = 2 * time.Minute
+	webhookBatchSize    = 100
+	webhookListLimit    = 100
+)
+
+// WebhookEvents lists every event a subscription can filter on
+var WebhookEvents = []string{
+	EventSecretCreated,
+	EventSecretUpdated,
+	EventSecretRotated,
+	EventSecretRevoked,
+	EventSecretDeleted,
+	EventProjectCreated,
+	EventProjectUpdated,
+	EventProjectDeleted,
+	EventProjectTransferred,
+	EventLeaseRevoked,
+	EventLeaseExpired,
+}
+
+type WebhookService struct {
+	repo         *repository.WebhookRepository
+	Authorizer   *Authorizer
+	AuditService *AuditService
+	client       *http.Client
+}
+
+func NewWebhookService(repo *repository.WebhookRepository, authorizer *Authorizer, auditService *AuditService) *WebhookService {
+	return &WebhookService{
+		repo:         repo,
+		Authorizer:   authorizer,
+		AuditService: auditService,
+		client:       &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+type WebhookEvent struct {
+	Event      string         `json:"event"`
+	ProjectID  uuid.UUID      `json:"project_id"`
+	SecretID   *uuid.UUID     `json:"secret_id,omitempty"`
+	ActorID    *uuid.UUID     `json:"actor_id,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       map[string]any `json:"data,omitempty"`
+}
+
+// ------------------------------------------------------------
+// Subscriptions
+// ------------------------------------------------------------
+
+// CreateSubscription registers a webhook and returns it together with the
+// plaintext signing secret, which is only ever shown here.
+func (s *WebhookService) CreateSubscription(
+	ctx context.Context,
+	userID string,
+	projectID string,
+	webhookURL string,
+	events []string,
+) (*models.WebhookSubscription, string, error) {
+
+	userUUID := uuid.MustParse(userID)
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, "", err
+	}
+
+	u, err := url.Parse(webhookURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, "", errors.New("url must be an http(s) url")
+	}
+	for _, filter := range events {
+		if !validEventFilter(filter) {
+			return nil, "", errors.New("unknown event filter: " + filter)
+		}
+	}
+
+	signingSecret, err := utils.RandomToken("whsec_", 32)
+	if err != nil {
+		return nil, "", err
+	}
+	encrypted, err := utils.Encrypt(signingSecret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sub := &models.WebhookSubscription{
+		ProjectID:     project.ID,
+		URL:           webhookURL,
+		Events:        events,
+		SigningSecret: encrypted,
+		Enabled:       true,
+		CreatedBy:     userUUID,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, "", err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"CREATE_WEBHOOK",
+		"Webhook subscription created for "+u.Host,
+	)
+
+	return sub, signingSecret, nil
+}
+
+func (s *WebhookService) ListSubscriptions(ctx context.Context, userID string, projectID string) ([]models.WebhookSubscription, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetSubscriptionsByProject(ctx, project.ID.String())
+}
+
+func (s *WebhookService) DeleteSubscription(ctx context.Context, userID string, projectID string, subscriptionID string) error {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return err
+	}
+
+	sub, err := s.repo.GetSubscriptionByID(ctx, subscriptionID)
+	if err != nil || sub == nil || sub.ProjectID != project.ID {
+		return errors.New("webhook not found")
+	}
+
+	if err := s.repo.DeleteSubscription(ctx, subscriptionID); err != nil {
+		return err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"DELETE_WEBHOOK",
+		"Webhook subscription deleted",
+	)
+	return nil
+}
+
+// ListDeliveries shows the delivery queue of a project; status "dead" gives
+// the dead-letter view.
+func (s *WebhookService) ListDeliveries(ctx context.Context, userID string, projectID string, status string) ([]models.WebhookDelivery, error) {
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+	if status != "" && status != DeliveryPending && status != DeliveryDelivered && status != DeliveryDead {
+		return nil, errors.New("invalid status")
+	}
+	return s.repo.GetDeliveriesByProject(ctx, project.ID.String(), status, webhookListLimit)
+}
+
+// RetryDelivery puts a dead delivery back into the queue
+func (s *WebhookService) RetryDelivery(ctx context.Context, userID string, projectID string, deliveryID string) (*models.WebhookDelivery, error) {
+	userUUID := uuid.MustParse(userID)
+
+	project, err := s.Authorizer.Authorize(ctx, userID, projectID, PermManage)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.GetDeliveryByID(ctx, deliveryID)
+	if err != nil || delivery == nil || delivery.ProjectID != project.ID {
+		return nil, errors.New("delivery not found")
+	}
+	if delivery.Status != DeliveryDead {
+		return nil, errors.New("only dead deliveries can be retried")
+	}
+
+	delivery.Status = DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&project.ID,
+		nil,
+		"RETRY_WEBHOOK_DELIVERY",
+		"Dead webhook delivery "+delivery.ID.String()+" re-queued",
+	)
+	return delivery, nil
+}
+
+// ------------------------------------------------------------
+// Producing and dispatching events
+// ------------------------------------------------------------
+
+// Emit queues the event for every subscription of the project whose filter
+// matches. Failures are logged and never fail the caller.
+func (s *WebhookService) Emit(ctx context.Context, projectID uuid.UUID, secretID *uuid.UUID, actorID *uuid.UUID, event string, data map[string]any) {
+	subs, err := s.repo.GetSubscriptionsByProject(ctx, projectID.String())
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] cannot load subscriptions for project %s: %v", projectID, err)
+		return
+	}
+
+	payload, err := json.Marshal(WebhookEvent{
+		Event:      event,
+		ProjectID:  projectID,
+		SecretID:   secretID,
+		ActorID:    actorID,
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+	if err != nil {
+		log.Printf("[WEBHOOK ERROR] cannot encode %s: %v", event, err)
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	for _, sub := range subs {
+		if !sub.Enabled || !matchesEventFilter(sub.Events, event) {
+			continue
+		}
+		deliveries = append(deliveries, models.WebhookDelivery{
+			ID:             uuid.New(),
+			SubscriptionID: sub.ID,
+			ProjectID:      projectID,
+			Event:          event,
+			Payload:        string(payload),
+			Status:         DeliveryPending,
+			NextAttemptAt:  time.Now(),
+			CreatedAt:      time.Now(),
+		})
+	}
+
+	if err := s.repo.EnqueueDeliveries(ctx, deliveries); err != nil {
+		log.Printf("[WEBHOOK ERROR] cannot queue %s: %v", event, err)
+	}
+}
+
+// DispatchDue sends every due delivery once and returns how many were attempted.
+// It is driven by the dispatcher job in main.
+func (s *WebhookService) DispatchDue(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.ClaimDueDeliveries(ctx, time.Now(), webhookClaimTimeout, webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	subs := map[uuid.UUID]*models.WebhookSubscription{}
+	for i := range deliveries {
+		delivery := &deliveries[i]
+
+		sub, ok := subs[delivery.SubscriptionID]
+		if !ok {
+			sub, err = s.repo.GetSubscriptionByID(ctx, delivery.SubscriptionID.String())
+			if err != nil {
+				log.Printf("[WEBHOOK ERROR] cannot load subscription %s: %v", delivery.SubscriptionID, err)
+				continue
+			}
+			subs[delivery.SubscriptionID] = sub
+		}
+
+		if sub == nil {
+			s.markFailed(ctx, delivery, 0, errors.New("subscription was deleted"), true)
+			continue
+		}
+
+		status, err := s.send(ctx, sub, delivery)
+		if err != nil {
+			s.markFailed(ctx, delivery, status, err, false)
+			continue
+		}
+
+		now := time.Now()
+		delivery.Status = DeliveryDelivered
+		delivery.Attempts += 1
+		delivery.DeliveredAt = &now
+		delivery.LastStatusCode = &status
+		delivery.LastError = nil
+		if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+			log.Printf("[WEBHOOK ERROR] cannot update delivery %s: %v", delivery.ID, err)
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+func (s *WebhookService) send(ctx context.Context, sub *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	key, err := utils.Decrypt(sub.SigningSecret)
+	if err != nil {
+		return 0, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.URL, bytes.NewReader([]byte(delivery.Payload)))
+	if err != nil {
+		return 0, err
+	}
+
+	// the timestamp is part of the signed content so receivers can reject replays
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Cryptex-Event", delivery.Event)
+	req.Header.Set("X-Cryptex-Delivery", delivery.ID.String())
+	req.Header.Set("X-Cryptex-Timestamp", timestamp)
+	req.Header.Set(utils.SignatureHeader, utils.SignPayload([]byte(key), []byte(timestamp+"."+delivery.Payload)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// markFailed schedules a retry with exponential backoff, or moves the
+// delivery to the dead-letter state once it runs out of attempts
+func (s *WebhookService) markFailed(ctx context.Context, delivery *models.WebhookDelivery, status int, cause error, permanent bool) {
+	message := cause.Error()
+
+	delivery.Attempts += 1
+	delivery.LastError = &message
+	if status != 0 {
+		delivery.LastStatusCode = &status
+	}
+
+	if permanent || delivery.Attempts >= webhookMaxAttempts {
+		delivery.Status = DeliveryDead
+		log.Printf("[WEBHOOK] delivery %s is dead after %d attempts: %s", delivery.ID, delivery.Attempts, message)
+	} else {
+		delivery.NextAttemptAt = time.Now().Add(webhookBackoff(delivery.Attempts))
+	}
+
+	if err := s.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Printf("[WEBHOOK ERROR] cannot update delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func webhookBackoff(attempts int) time.Duration {
+	backoff := webhookRetryBase
+	for i := 1; i < attempts && backoff < webhookRetryMax; i++ {
+		backoff *= 2
+	}
+	if backoff > webhookRetryMax {
+		backoff = webhookRetryMax
+	}
+	return backoff
+}
+
+// matchesEventFilter reports whether event passes the filter list.
+// An empty list or "*" matches everything, "secret.*" matches a whole family.
+func matchesEventFilter(filters []string, event string) bool {
+	if len(filters) == 0 {
+		return true
+	}
+	for _, filter := range filters {
+		if filter == "*" || filter == event {
+			return true
+		}
+		if strings.HasSuffix(filter, ".*") && strings.HasPrefix(event, strings.TrimSuffix(filter, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func validEventFilter(filter string) bool {
+	for _, event := range WebhookEvents {
+		if matchesEventFilter([]string{filter}, event) {
+			return true
+		}
+	}
+	return false
+}
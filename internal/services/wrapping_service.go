@@ -0,0 +1,167 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/akansha204/cryptex-secretservice/internal/models"
+	"github.com/akansha204/cryptex-secretservice/internal/repository"
+	"github.com/akansha204/cryptex-secretservice/internal/utils"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultWrapTTL = 5 * time.Minute
+	maxWrapTTL     = 24 * time.Hour
+)
+
+type WrappingService struct {
+	repo          *repository.WrappingRepository
+	SecretService *SecretService
+	AuditService  *AuditService
+}
+
+func NewWrappingService(repo *repository.WrappingRepository, secretService *SecretService, auditService *AuditService) *WrappingService {
+	return &WrappingService{
+		repo:          repo,
+		SecretService: secretService,
+		AuditService:  auditService,
+	}
+}
+
+// UnwrappedSecret is what a wrapped token is exchanged for
+type UnwrappedSecret struct {
+	SecretID  uuid.UUID `json:"secret_id"`
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	Plaintext string    `json:"plaintext"`
+	LeaseID   *string   `json:"lease_id,omitempty"`
+}
+
+// Wrap reads a secret on behalf of the user and stores the result behind a
+// single-use token. The read goes through the normal secret checks, so it
+// counts against read limits and issues a lease like any other read.
+func (s *WrappingService) Wrap(ctx context.Context, userID string, projectID string, secretID string, ttl *time.Duration) (*models.WrappedToken, string, error) {
+	userUUID := uuid.MustParse(userID)
+
+	wrapTTL := defaultWrapTTL
+	if ttl != nil {
+		if *ttl <= 0 || *ttl > maxWrapTTL {
+			return nil, "", errors.New("wrap ttl must be between 1s and " + maxWrapTTL.String())
+		}
+		wrapTTL = *ttl
+	}
+
+	secret, plaintext, lease, err := s.SecretService.GetSecretByID(ctx, userID, projectID, secretID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	encrypted, err := utils.Encrypt(plaintext)
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, err := utils.RandomToken("cx_wrap_", 32)
+	if err != nil {
+		return nil, "", err
+	}
+
+	wrapped := &models.WrappedToken{
+		ID:            uuid.New(),
+		TokenHash:     utils.HashToken(token),
+		ProjectID:     secret.ProjectID,
+		SecretID:      secret.ID,
+		CreatedBy:     userUUID,
+		Value:         encrypted,
+		SecretName:    secret.Name,
+		SecretVersion: secret.Version,
+		ExpiresAt:     time.Now().Add(wrapTTL),
+		CreatedAt:     time.Now(),
+	}
+	if lease != nil {
+		wrapped.LeaseID = &lease.ID
+	}
+
+	if err := s.repo.CreateToken(ctx, wrapped); err != nil {
+		return nil, "", err
+	}
+
+	s.AuditService.Log(
+		ctx,
+		&userUUID,
+		&secret.ProjectID,
+		&secret.ID,
+		"WRAP_SECRET",
+		"Wrapped token "+wrapped.ID.String()+" created, valid until "+wrapped.ExpiresAt.Format(time.RFC3339),
+	)
+
+	return wrapped, token, nil
+}
+
+// Unwrap exchanges a wrapped token for the secret it holds. It needs no
+// authentication: the token is the credential, and it works exactly once.
+func (s *WrappingService) Unwrap(ctx context.Context, token string) (*UnwrappedSecret, error) {
+	tokenHash := utils.HashToken(token)
+	now := time.Now()
+
+	wrapped, err := s.repo.ClaimToken(ctx, tokenHash, now)
+	if err != nil {
+		return nil, err
+	}
+	if wrapped == nil {
+		return nil, s.rejectUnwrap(ctx, tokenHash, now)
+	}
+
+	plaintext, err := utils.Decrypt(wrapped.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ScrubToken(ctx, wrapped.ID.String()); err != nil {
+		log.Printf("[WRAP ERROR] cannot scrub wrapped token %s: %v", wrapped.ID, err)
+	}
+
+	s.AuditService.Log(
+		ctx,
+		nil,
+		&wrapped.ProjectID,
+		&wrapped.SecretID,
+		"UNWRAP_SECRET",
+		"Wrapped token "+wrapped.ID.String()+" unwrapped",
+	)
+
+	return &UnwrappedSecret{
+		SecretID:  wrapped.SecretID,
+		Name:      wrapped.SecretName,
+		Version:   wrapped.SecretVersion,
+		Plaintext: plaintext,
+		LeaseID:   wrapped.LeaseID,
+	}, nil
+}
+
+// rejectUnwrap explains why a token could not be claimed and audits reuse,
+// which means the token leaked or was intercepted
+func (s *WrappingService) rejectUnwrap(ctx context.Context, tokenHash string, now time.Time) error {
+	wrapped, err := s.repo.GetTokenByHash(ctx, tokenHash)
+	if err != nil || wrapped == nil {
+		return errors.New("invalid token")
+	}
+
+	if wrapped.UnwrappedAt != nil {
+		s.AuditService.Log(
+			ctx,
+			nil,
+			&wrapped.ProjectID,
+			&wrapped.SecretID,
+			"WRAP_TOKEN_REUSED",
+			"Wrapped token "+wrapped.ID.String()+" presented again after it was unwrapped at "+wrapped.UnwrappedAt.Format(time.RFC3339),
+		)
+		return errors.New("token has already been used")
+	}
+	if !wrapped.ExpiresAt.After(now) {
+		return errors.New("token has expired")
+	}
+	return errors.New("invalid token")
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AppRoleIssuer   = "cryptex-secret-service"
+	AppRoleAudience = "cryptex-approle"
+)
+
+// AppRoleClaims are the claims of a token issued by an AppRole login. Subject
+// is the role ID.
+type AppRoleClaims struct {
+	jwt.RegisteredClaims
+	RoleName string   `json:"role_name"`
+	Paths    []string `json:"paths"`  // path globs the token is limited to
+	Scopes   []string `json:"scopes"` // capabilities the token has on them
+}
+
+// AppRoleSigningKey returns the HMAC key for AppRole tokens: APPROLE_SIGNING_KEY
+// when set, otherwise a key derived from SECRET_ENCRYPTION_KEY
+func AppRoleSigningKey() ([]byte, error) {
+	if raw := os.Getenv("APPROLE_SIGNING_KEY"); raw != "" {
+		key, err := base64.StdEncoding.DecodeString(raw)
+		if err != nil {
+			key = []byte(raw)
+		}
+		if len(key) < 32 {
+			return nil, errors.New("APPROLE_SIGNING_KEY must be at least 32 bytes")
+		}
+		return key, nil
+	}
+
+	raw := os.Getenv("SECRET_ENCRYPTION_KEY")
+	if raw == "" {
+		return nil, errors.New("SECRET_ENCRYPTION_KEY is not set")
+	}
+	return hkdf.Key(sha256.New, []byte(raw), nil, "cryptex-approle-v1", 32)
+}
+
+// SignAppRoleToken signs the claims as an HS256 JWT
+func SignAppRoleToken(key []byte, claims *AppRoleClaims) (string, error) {
+	claims.Issuer = AppRoleIssuer
+	claims.Audience = jwt.ClaimStrings{AppRoleAudience}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(key)
+}
+
+// ParseAppRoleToken verifies the signature, issuer, audience and lifetime of an AppRole token
+func ParseAppRoleToken(key []byte, token string) (*AppRoleClaims, error) {
+	claims := &AppRoleClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(AppRoleIssuer),
+		jwt.WithAudience(AppRoleAudience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(5*time.Second),
+	)
+	if err != nil || !parsed.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// NormalizeCIDRs validates an allowlist of CIDRs. Bare addresses become
+// single-host networks.
+func NormalizeCIDRs(values []string) ([]string, error) {
+	var out []string
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, errors.New("invalid ip address " + value)
+			}
+			if ip.To4() != nil {
+				value += "/32"
+			} else {
+				value += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, errors.New("invalid cidr " + value)
+		}
+		out = append(out, network.String())
+	}
+	return out, nil
+}
+
+// IPAllowed reports whether ip is inside one of the CIDRs; an empty list allows every address
+func IPAllowed(ip string, cidrs []string) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FormatPassword   = "password"
+	FormatHex        = "hex"
+	FormatBase64URL  = "base64url"
+	FormatUUID       = "uuid"
+	FormatPassphrase = "passphrase"
+)
+
+const (
+	defaultGenerateLength  = 32
+	maxGenerateLength      = 1024
+	defaultPassphraseWords = 8
+	maxPassphraseWords     = 64
+
+	lowerChars  = "abcdefghijklmnopqrstuvwxyz"
+	upperChars  = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digitChars  = "0123456789"
+	symbolChars = "!@#$%^&*()-_=+[]{};:,.<>/?~|"
+
+	// characters that are easy to confuse when read or typed by a human
+	ambiguousChars = "Il1O0o|"
+)
+
+// GeneratePolicy describes how a secret value should be generated.
+// Character classes only apply to the password format; when none are
+// selected, lowercase, uppercase and digits are used.
+type GeneratePolicy struct {
+	Format           string `json:"format"`
+	Length           int    `json:"length"`
+	Lowercase        bool   `json:"lowercase"`
+	Uppercase        bool   `json:"uppercase"`
+	Digits           bool   `json:"digits"`
+	Symbols          bool   `json:"symbols"`
+	ExcludeAmbiguous bool   `json:"exclude_ambiguous"`
+	Words            int    `json:"words"`
+	Separator        string `json:"separator"`
+}
+
+// GenerateSecret produces a random value from crypto/rand according to the policy.
+func GenerateSecret(policy GeneratePolicy) (string, error) {
+	switch policy.Format {
+	case "", FormatPassword:
+		return generatePassword(policy)
+	case FormatHex:
+		return generateEncoded(policy, hex.EncodeToString)
+	case FormatBase64URL:
+		return generateEncoded(policy, base64.RawURLEncoding.EncodeToString)
+	case FormatUUID:
+		id, err := uuid.NewRandomFromReader(rand.Reader)
+		if err != nil {
+			return "", fmt.Errorf("cannot generate uuid: %w", err)
+		}
+		return id.String(), nil
+	case FormatPassphrase:
+		return generatePassphrase(policy)
+	default:
+		return "", fmt.Errorf("unsupported generate format: %s", policy.Format)
+	}
+}
+
+func generateLength(policy GeneratePolicy) (int, error) {
+	if policy.Length == 0 {
+		return defaultGenerateLength, nil
+	}
+	if policy.Length < 1 || policy.Length > maxGenerateLength {
+		return 0, fmt.Errorf("length must be between 1 and %d", maxGenerateLength)
+	}
+	return policy.Length, nil
+}
+
+func generatePassword(policy GeneratePolicy) (string, error) {
+	length, err := generateLength(policy)
+	if err != nil {
+		return "", err
+	}
+
+	if !policy.Lowercase && !policy.Uppercase && !policy.Digits && !policy.Symbols {
+		policy.Lowercase, policy.Uppercase, policy.Digits = true, true, true
+	}
+
+	var classes []string
+	for _, class := range []struct {
+		enabled bool
+		chars   string
+	}{
+		{policy.Lowercase, lowerChars},
+		{policy.Uppercase, upperChars},
+		{policy.Digits, digitChars},
+		{policy.Symbols, symbolChars},
+	} {
+		if !class.enabled {
+			continue
+		}
+		chars := class.chars
+		if policy.ExcludeAmbiguous {
+			chars = stripChars(chars, ambiguousChars)
+		}
+		classes = append(classes, chars)
+	}
+
+	if length < len(classes) {
+		return "", fmt.Errorf("length must be at least %d to include every character class", len(classes))
+	}
+
+	// one character from every selected class, the rest from the full alphabet
+	alphabet := strings.Join(classes, "")
+	out := make([]byte, 0, length)
+	for _, chars := range classes {
+		c, err := randomChar(chars)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+	for len(out) < length {
+		c, err := randomChar(alphabet)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, c)
+	}
+
+	if err := shuffle(out); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// generateEncoded draws enough random bytes to fill length characters of the
+// given encoding and trims the result to exactly length characters.
+func generateEncoded(policy GeneratePolicy, encode func([]byte) string) (string, error) {
+	length, err := generateLength(policy)
+	if err != nil {
+		return "", err
+	}
+
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("cannot read random bytes: %w", err)
+	}
+	return encode(buf)[:length], nil
+}
+
+func generatePassphrase(policy GeneratePolicy) (string, error) {
+	words := policy.Words
+	if words == 0 {
+		words = defaultPassphraseWords
+	}
+	if words < 1 || words > maxPassphraseWords {
+		return "", fmt.Errorf("words must be between 1 and %d", maxPassphraseWords)
+	}
+
+	separator := policy.Separator
+	if separator == "" {
+		separator = "-"
+	}
+
+	out := make([]string, words)
+	for i := range out {
+		n, err := randomInt(len(passphraseWords))
+		if err != nil {
+			return "", err
+		}
+		out[i] = passphraseWords[n]
+	}
+	return strings.Join(out, separator), nil
+}
+
+func randomInt(max int) (int, error) {
+	if max <= 0 {
+		return 0, errors.New("empty alphabet")
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(max)))
+	if err != nil {
+		return 0, fmt.Errorf("cannot read random number: %w", err)
+	}
+	return int(n.Int64()), nil
+}
+
+func randomChar(chars string) (byte, error) {
+	n, err := randomInt(len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[n], nil
+}
+
+// Fisher-Yates shuffle backed by crypto/rand
+func shuffle(b []byte) error {
+	for i := len(b) - 1; i > 0; i-- {
+		j, err := randomInt(i + 1)
+		if err != nil {
+			return err
+		}
+		b[i], b[j] = b[j], b[i]
+	}
+	return nil
+}
+
+func stripChars(s, remove string) string {
+	return strings.Map(func(r rune) rune {
+		if strings.ContainsRune(remove, r) {
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,164 @@
+package utils
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// compileGlob turns a path glob into a regexp: "*" and "?" stay within one
+// "/" separated segment, "**" matches across segments
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+// ValidatePathGlob checks that a policy path glob is usable
+func ValidatePathGlob(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return errors.New("path cannot be empty")
+	}
+	if strings.HasPrefix(pattern, "/") {
+		return errors.New("path must not start with /")
+	}
+	_, err := compileGlob(pattern)
+	return err
+}
+
+// MatchPathGlob reports whether path matches the glob pattern
+func MatchPathGlob(pattern string, path string) bool {
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(path)
+}
+
+// globToken is one element of a path glob: a literal character, "?", "*" or "**"
+type globToken struct {
+	wildcard string
+	ch       byte
+}
+
+func (t globToken) star() bool {
+	return t.wildcard == "*" || t.wildcard == "**"
+}
+
+func tokenizeGlob(pattern string) []globToken {
+	var tokens []globToken
+	for i := 0; i < len(pattern); i++ {
+		switch ch := pattern[i]; ch {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				tokens = append(tokens, globToken{wildcard: "**"})
+				i++
+			} else {
+				tokens = append(tokens, globToken{wildcard: "*"})
+			}
+		case '?':
+			tokens = append(tokens, globToken{wildcard: "?"})
+		default:
+			tokens = append(tokens, globToken{ch: ch})
+		}
+	}
+	return tokens
+}
+
+// GlobCovers reports whether every path matched by inner is also matched by
+// outer. Wildcards of inner are only covered by wildcards of outer that match
+// at least as much ("**" covers everything, "*" covers "*", "?" and
+// characters other than "/", "?" covers "?" and one such character), so a
+// false result can also mean the answer is not certain.
+func GlobCovers(outer string, inner string) bool {
+	o, n := tokenizeGlob(outer), tokenizeGlob(inner)
+	memo := map[[2]int]bool{}
+	var covers func(i, j int) bool
+	covers = func(i, j int) bool {
+		if i == len(o) {
+			return j == len(n)
+		}
+		key := [2]int{i, j}
+		if result, ok := memo[key]; ok {
+			return result
+		}
+		var result bool
+		switch o[i].wildcard {
+		case "**":
+			result = covers(i+1, j) || (j < len(n) && covers(i, j+1))
+		case "*":
+			result = covers(i+1, j) ||
+				(j < len(n) && n[j].wildcard != "**" && !(n[j].wildcard == "" && n[j].ch == '/') && covers(i, j+1))
+		case "?":
+			result = j < len(n) && (n[j].wildcard == "?" || (n[j].wildcard == "" && n[j].ch != '/')) && covers(i+1, j+1)
+		default:
+			result = j < len(n) && n[j].wildcard == "" && n[j].ch == o[i].ch && covers(i+1, j+1)
+		}
+		memo[key] = result
+		return result
+	}
+	return covers(0, 0)
+}
+
+// GlobsOverlap reports whether some path is matched by both globs
+func GlobsOverlap(a string, b string) bool {
+	x, y := tokenizeGlob(a), tokenizeGlob(b)
+	memo := map[[2]int]bool{}
+	var overlap func(i, j int) bool
+	overlap = func(i, j int) bool {
+		if i == len(x) && j == len(y) {
+			return true
+		}
+		key := [2]int{i, j}
+		if result, ok := memo[key]; ok {
+			return result
+		}
+		// a star may match nothing
+		result := (i < len(x) && x[i].star() && overlap(i+1, j)) ||
+			(j < len(y) && y[j].star() && overlap(i, j+1))
+		// or both take the same character; two stars taking one together adds nothing
+		if !result && i < len(x) && j < len(y) && !(x[i].star() && y[j].star()) && sameCharacter(x[i], y[j]) {
+			next := func(t globToken, k int) int {
+				if t.star() {
+					return k
+				}
+				return k + 1
+			}
+			result = overlap(next(x[i], i), next(y[j], j))
+		}
+		memo[key] = result
+		return result
+	}
+	return overlap(0, 0)
+}
+
+// sameCharacter reports whether two tokens can match the same character
+func sameCharacter(a globToken, b globToken) bool {
+	switch {
+	case a.wildcard == "**" || b.wildcard == "**":
+		return true
+	case a.wildcard == "" && b.wildcard == "":
+		return a.ch == b.ch
+	case a.wildcard == "":
+		return a.ch != '/'
+	case b.wildcard == "":
+		return b.ch != '/'
+	}
+	return true
+}
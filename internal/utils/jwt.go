@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// asymmetric algorithms only: the service never holds the issuer's secret
+var jwtMethods = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// JWTVerifier verifies tokens of an external identity provider
+type JWTVerifier struct {
+	issuer  string
+	keyfunc jwt.Keyfunc
+	parser  *jwt.Parser
+}
+
+func NewJWTVerifier(issuer string, audience string, keyfunc jwt.Keyfunc) *JWTVerifier {
+	return &JWTVerifier{
+		issuer:  issuer,
+		keyfunc: keyfunc,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(jwtMethods),
+			jwt.WithIssuer(issuer),
+			jwt.WithAudience(audience),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(5*time.Second),
+		),
+	}
+}
+
+// LoadJWTVerifier configures a verifier from JWT_ISSUER, JWT_AUDIENCE and
+// either JWT_PUBLIC_KEY_FILE (PEM) or JWT_JWKS_URL, refreshed every
+// JWT_JWKS_REFRESH (default 1 hour)
+func LoadJWTVerifier() (*JWTVerifier, error) {
+	issuer := os.Getenv("JWT_ISSUER")
+	audience := os.Getenv("JWT_AUDIENCE")
+	if issuer == "" || audience == "" {
+		return nil, errors.New("JWT_ISSUER and JWT_AUDIENCE are required")
+	}
+
+	keyFile := os.Getenv("JWT_PUBLIC_KEY_FILE")
+	jwksURL := os.Getenv("JWT_JWKS_URL")
+	switch {
+	case keyFile != "" && jwksURL != "":
+		return nil, errors.New("set JWT_PUBLIC_KEY_FILE or JWT_JWKS_URL, not both")
+
+	case keyFile != "":
+		keys, err := LoadPublicKeys(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		set := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, key)
+		}
+		return NewJWTVerifier(issuer, audience, func(*jwt.Token) (interface{}, error) {
+			return set, nil
+		}), nil
+
+	case jwksURL != "":
+		refresh := time.Hour
+		if raw := os.Getenv("JWT_JWKS_REFRESH"); raw != "" {
+			d, err := ParseDuration(raw)
+			if err != nil || d < jwksMinRefetch {
+				return nil, errors.New("JWT_JWKS_REFRESH must be a duration of at least 30s")
+			}
+			refresh = d
+		}
+		jwks := NewJWKS(jwksURL, refresh)
+		jwks.get("") // fetch now so a wrong URL shows up in the startup logs
+		return NewJWTVerifier(issuer, audience, jwks.Keyfunc), nil
+	}
+	return nil, errors.New("JWT_PUBLIC_KEY_FILE or JWT_JWKS_URL is required")
+}
+
+// Issuer is the iss the verifier accepts
+func (v *JWTVerifier) Issuer() string {
+	return v.issuer
+}
+
+// Parse verifies the signature, issuer, audience, expiry and not-before of a
+// token and returns its claims
+func (v *JWTVerifier) Parse(token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parsed, err := v.parser.ParseWithClaims(token, claims, v.keyfunc)
+	if err != nil || !parsed.Valid {
+		return nil, errors.New("invalid token")
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return nil, errors.New("token does not contain a subject")
+	}
+	return claims, nil
+}
+
+// TokenIssuer reads the iss of a JWT without verifying it, to pick the
+// verifier to hand it to
+func TokenIssuer(token string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return ""
+	}
+	issuer, _ := claims["iss"].(string)
+	return issuer
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+const defaultProxyHeader = "X-Forwarded-For"
+
+// LoadProxyConfig reads TRUSTED_PROXIES, comma separated addresses or CIDRs
+// of the gateways in front of the service, and PROXY_HEADER, the header they
+// put the client address in (default X-Forwarded-For). Only requests coming
+// from a trusted proxy get their address from the header, the others keep
+// the address of the connection. The first valid address in the header is
+// taken, so the gateway must overwrite the header instead of appending to
+// what the client sent. It returns no proxies when the variable is not set.
+func LoadProxyConfig() (header string, proxies []string, err error) {
+	proxies, err = NormalizeCIDRs(strings.Split(os.Getenv("TRUSTED_PROXIES"), ","))
+	if err != nil {
+		return "", nil, errors.New("TRUSTED_PROXIES: " + err.Error())
+	}
+	header = strings.TrimSpace(os.Getenv("PROXY_HEADER"))
+	if header == "" {
+		header = defaultProxyHeader
+	}
+	return header, proxies, nil
+}
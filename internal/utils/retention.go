@@ -0,0 +1,17 @@
+package utils
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+// PurgeRetention is how long soft-deleted projects and secrets stay in the
+// trash before the purge job removes them (PURGE_DAYS, default 7 days)
+func PurgeRetention() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("PURGE_DAYS"))
+	if err != nil || days < 1 {
+		days = 7
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	shareKeyInfo          = "cryptex-share-v1"
+	sharePassphraseRounds = 600000 // OWASP recommendation for PBKDF2-HMAC-SHA256
+)
+
+// DeriveShareKey derives the AES-256 key of a share link from the random link
+// fragment and the stored salt. With a passphrase, a PBKDF2 hash of it is mixed
+// in, so both the fragment and the passphrase are needed to decrypt.
+func DeriveShareKey(fragment []byte, salt []byte, passphrase string) ([]byte, error) {
+	secret := append([]byte{}, fragment...)
+	if passphrase != "" {
+		stretched, err := pbkdf2.Key(sha256.New, passphrase, salt, sharePassphraseRounds, 32)
+		if err != nil {
+			return nil, err
+		}
+		secret = append(secret, stretched...)
+	}
+	return hkdf.Key(sha256.New, secret, salt, shareKeyInfo, 32)
+}
+
+// EncryptWithKey works like Encrypt but with a caller supplied 32 byte key
+// instead of SECRET_ENCRYPTION_KEY
+func EncryptWithKey(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", fmt.Errorf("cannot generate nonce: %w", err)
+	}
+
+	out := append(nonce, gcm.Seal(nil, nonce, []byte(plaintext), nil)...)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// DecryptWithKey reverses EncryptWithKey. A wrong key fails like tampered data.
+func DecryptWithKey(key []byte, ciphertextB64 string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := base64.StdEncoding.DecodeString(ciphertextB64)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64 ciphertext: %w", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decryption failed or data tampered: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid key length: got %d bytes, need 32", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AES cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
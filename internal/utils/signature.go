@@ -0,0 +1,18 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignatureHeader carries the HMAC of outbound request bodies so receivers
+// can verify that a call really came from Cryptex.
+const SignatureHeader = "X-Cryptex-Signature"
+
+// SignPayload returns the hex encoded HMAC-SHA256 of payload, prefixed with the algorithm.
+func SignPayload(key []byte, payload []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
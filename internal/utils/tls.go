@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// how often the certificate files are checked for changes
+const tlsReloadCheck = 10 * time.Second
+
+// LoadTLSConfig builds the listener's TLS config from TLS_CERT_FILE and
+// TLS_KEY_FILE, or returns nil to serve plain HTTP when they are not set.
+// TLS_CLIENT_CA_FILE turns on client certificates, verified against that CA
+// bundle: TLS_CLIENT_AUTH=optional (default) lets clients without one fall
+// back to other auth, require rejects them during the handshake. The files
+// are read again when they change, so certificates can be renewed without a
+// restart.
+func LoadTLSConfig() (*tls.Config, error) {
+	certFile := os.Getenv("TLS_CERT_FILE")
+	keyFile := os.Getenv("TLS_KEY_FILE")
+	caFile := os.Getenv("TLS_CLIENT_CA_FILE")
+	if certFile == "" && keyFile == "" {
+		if caFile != "" {
+			return nil, errors.New("TLS_CLIENT_CA_FILE needs TLS_CERT_FILE and TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+
+	clientAuth := tls.NoClientCert
+	if caFile != "" {
+		switch strings.ToLower(os.Getenv("TLS_CLIENT_AUTH")) {
+		case "", "optional":
+			clientAuth = tls.VerifyClientCertIfGiven
+		case "require":
+			clientAuth = tls.RequireAndVerifyClientCert
+		default:
+			return nil, errors.New("TLS_CLIENT_AUTH must be optional or require")
+		}
+	}
+
+	reloader := &certReloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if err := reloader.load(); err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, pool := reloader.current()
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+				ClientCAs:    pool,
+				ClientAuth:   clientAuth,
+			}, nil
+		},
+	}, nil
+}
+
+// certReloader keeps the server certificate and client CA pool in sync with their files
+type certReloader struct {
+	certFile, keyFile, caFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  []time.Time
+	checkedAt time.Time
+}
+
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) > tlsReloadCheck {
+		r.checkedAt = time.Now()
+		if !sameTimes(r.modTimes, r.fileTimes()) {
+			if err := r.load(); err != nil {
+				// keep serving the certificate we have until the files are fixed
+				log.Printf("[TLS ERROR] cannot reload certificates: %v", err)
+			} else {
+				log.Println("[TLS] certificates reloaded")
+			}
+		}
+	}
+	return r.cert, r.pool
+}
+
+func (r *certReloader) load() error {
+	modTimes := r.fileTimes()
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		pem, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New(r.caFile + " contains no certificate")
+		}
+	}
+
+	r.cert = &cert
+	r.pool = pool
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *certReloader) fileTimes() []time.Time {
+	var times []time.Time
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		var modTime time.Time
+		if info, err := os.Stat(file); file != "" && err == nil {
+			modTime = info.ModTime()
+		}
+		times = append(times, modTime)
+	}
+	return times
+}
+
+func sameTimes(a []time.Time, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
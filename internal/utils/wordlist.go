@@ -0,0 +1,35 @@
+package utils
+
+// passphraseWords holds 256 short, distinct English words, so every word of a
+// generated passphrase carries 8 bits of entropy.
+var passphraseWords = [...]string{
+	"able", "acid", "aged", "also", "area", "army", "away", "baby", "back",
+	"bake", "ball", "band", "bank", "barn", "base", "bath", "bear", "beat",
+	"bell", "belt", "bend", "best", "bike", "bird", "bite", "blue", "boat",
+	"body", "bold", "bone", "book", "boot", "born", "boss", "both", "bowl",
+	"bulk", "burn", "bush", "busy", "cake", "calm", "camp", "card", "care",
+	"cart", "case", "cash", "cast", "cave", "cell", "chef", "chip", "city",
+	"clay", "clip", "club", "coal", "coat", "code", "coin", "cold", "cook",
+	"cool", "cope", "copy", "cord", "core", "corn", "cost", "crew", "crop",
+	"cube", "cure", "dark", "data", "dawn", "deal", "deck", "deep", "deer",
+	"desk", "dial", "dice", "diet", "dish", "dock", "door", "dose", "dove",
+	"down", "draw", "drop", "drum", "duck", "dune", "dust", "duty", "earn",
+	"east", "easy", "echo", "edge", "envy", "epic", "exit", "face", "fact",
+	"fair", "fall", "farm", "fast", "fern", "film", "find", "fire", "firm",
+	"fish", "five", "flag", "flat", "flow", "foam", "fold", "folk", "food",
+	"foot", "fork", "form", "fort", "fuel", "fund", "gain", "game", "gate",
+	"gear", "gift", "glad", "glow", "glue", "goal", "gold", "golf", "good",
+	"gown", "grab", "gray", "grid", "grin", "grip", "gulf", "hair", "half",
+	"hall", "hand", "harp", "hawk", "heat", "herb", "hero", "hike", "hill",
+	"hint", "hive", "hold", "home", "hood", "hook", "hope", "horn", "host",
+	"hour", "huge", "hunt", "idea", "inch", "iron", "isle", "item", "jade",
+	"jazz", "jeep", "join", "joke", "jump", "jury", "keen", "kept", "kick",
+	"kind", "king", "kite", "knee", "knot", "lace", "lake", "lamb", "lamp",
+	"land", "lane", "last", "lawn", "leaf", "lean", "left", "lens", "lift",
+	"lime", "line", "link", "lion", "list", "load", "loaf", "loan", "lock",
+	"loft", "long", "loop", "lord", "loud", "love", "luck", "lung", "made",
+	"mail", "main", "malt", "mane", "many", "maple", "mask", "meal", "melt",
+	"menu", "mild", "milk", "mill", "mind", "mint", "mist", "mode", "mole",
+	"monk", "mood", "moon", "moss", "moth", "much", "mule", "myth", "nail",
+	"name", "navy", "neck", "nest",
+}